@@ -0,0 +1,31 @@
+package audit
+
+import (
+	"context"
+	"net/http"
+	auditlog "production-go-api-template/pkg/audit"
+	"production-go-api-template/pkg/router"
+)
+
+type EventQuerier interface {
+	Query(ctx context.Context, filter auditlog.Filter) ([]auditlog.Event, error)
+}
+
+func ListEventsHandler(q EventQuerier, w http.ResponseWriter, r *http.Request) {
+	filter, err := ParseFilter(r.URL.Query())
+	if err != nil {
+		router.RespondWithError(r, w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	events, err := q.Query(r.Context(), filter)
+	if err != nil {
+		router.RespondWithError(r, w, http.StatusInternalServerError, "failed to query audit events", err)
+		return
+	}
+	if events == nil {
+		events = []auditlog.Event{}
+	}
+
+	router.RespondWithJSON(r, w, http.StatusOK, EventsResponse{Events: events, Total: len(events)})
+}
@@ -0,0 +1,77 @@
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	auditlog "production-go-api-template/pkg/audit"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+type EventsResponse struct {
+	Events []auditlog.Event `json:"events"`
+	Total  int              `json:"total"`
+}
+
+// ParseFilter reads the from, to, type, client_id, ip and limit query
+// parameters. Times are RFC 3339; from is inclusive and to exclusive.
+func ParseFilter(query url.Values) (auditlog.Filter, error) {
+	var errs []string
+	filter := auditlog.Filter{
+		Type:     auditlog.EventType(query.Get("type")),
+		ClientID: query.Get("client_id"),
+		IP:       query.Get("ip"),
+		Limit:    defaultLimit,
+	}
+
+	var err error
+	if v := query.Get("from"); v != "" {
+		if filter.From, err = time.Parse(time.RFC3339, v); err != nil {
+			errs = append(errs, "from must be an RFC 3339 timestamp")
+		}
+	}
+
+	if v := query.Get("to"); v != "" {
+		if filter.To, err = time.Parse(time.RFC3339, v); err != nil {
+			errs = append(errs, "to must be an RFC 3339 timestamp")
+		}
+	}
+
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		errs = append(errs, "from must be before to")
+	}
+
+	if filter.Type != "" && !slices.Contains(auditlog.EventTypes, filter.Type) {
+		errs = append(errs, fmt.Sprintf("type must be one of %s", eventTypeList()))
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > maxLimit {
+			errs = append(errs, fmt.Sprintf("limit must be between 1 and %d", maxLimit))
+		}
+		filter.Limit = limit
+	}
+
+	if len(errs) > 0 {
+		return filter, errors.New(strings.Join(errs, "; "))
+	}
+
+	return filter, nil
+}
+
+func eventTypeList() string {
+	names := make([]string, len(auditlog.EventTypes))
+	for i, t := range auditlog.EventTypes {
+		names[i] = string(t)
+	}
+	return strings.Join(names, ", ")
+}
@@ -2,7 +2,10 @@ package health
 
 import (
 	"net/http"
+	"production-go-api-template/config"
+	"production-go-api-template/pkg/contextkeys"
 	"production-go-api-template/pkg/router"
+	"production-go-api-template/pkg/validator"
 	"time"
 
 	"github.com/joeshaw/envdecode"
@@ -36,3 +39,18 @@ func (h *Health) CheckHandler(w http.ResponseWriter, r *http.Request) {
 
 	router.RespondWithJSON(r, w, http.StatusOK, healthcheck)
 }
+
+func TimeHandler(w http.ResponseWriter, r *http.Request) {
+	cfg, err := validator.ExtractAndValidateContext[*config.Conf](r.Context(), contextkeys.CtxKeyConfig)
+	if err != nil {
+		router.RespondWithError(r, w, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	now := time.Now().UTC()
+	router.RespondWithJSON(r, w, http.StatusOK, ServerTime{
+		Unix:           now.Unix(),
+		Time:           now,
+		MaxSkewSeconds: int64(cfg.Auth.TimestampSkew / time.Second),
+	})
+}
@@ -8,3 +8,11 @@ type Check struct {
 	Timestamp time.Time     `json:"Timestamp"`
 	Uptime    time.Duration `json:"Uptime"`
 }
+
+// ServerTime lets clients measure their clock offset before signing
+// requests; timestamps further than MaxSkewSeconds away are rejected.
+type ServerTime struct {
+	Unix           int64     `json:"unix"`
+	Time           time.Time `json:"time"`
+	MaxSkewSeconds int64     `json:"max_skew_seconds"`
+}
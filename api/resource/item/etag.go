@@ -0,0 +1,84 @@
+package item
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	headerETag    = "ETag"
+	headerIfMatch = "If-Match"
+
+	anyETag = "*"
+)
+
+var (
+	// ErrVersionConflict means the item changed since the client read it.
+	ErrVersionConflict = errors.New("item version does not match If-Match")
+	// ErrPreconditionRequired is returned for writes without If-Match when
+	// ITEMS_REQUIRE_IF_MATCH is set.
+	ErrPreconditionRequired = errors.New("If-Match header is required")
+)
+
+// Precondition is a parsed If-Match header. Any matches every version of an
+// existing item; otherwise the item must be at one of Versions.
+type Precondition struct {
+	Any      bool
+	Versions []int
+}
+
+// ETag formats an item version as a strong entity tag.
+func ETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// ParseIfMatch returns nil when the request has no If-Match header. Weak and
+// malformed entity tags never match, since If-Match uses strong comparison.
+func ParseIfMatch(h http.Header) *Precondition {
+	values := h.Values(headerIfMatch)
+	if len(values) == 0 {
+		return nil
+	}
+
+	pre := &Precondition{}
+	for _, value := range values {
+		for _, tag := range strings.Split(value, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == anyETag {
+				pre.Any = true
+				continue
+			}
+			if len(tag) < 2 || !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) {
+				continue
+			}
+			if version, err := strconv.Atoi(tag[1 : len(tag)-1]); err == nil {
+				pre.Versions = append(pre.Versions, version)
+			}
+		}
+	}
+	return pre
+}
+
+// missing reports that the item does not exist. RFC 9110 makes every
+// If-Match fail then, "*" included, so this is a failed precondition rather
+// than a 404.
+func (p *Precondition) missing(err error) error {
+	if p != nil && err != nil && strings.Contains(err.Error(), "not found") {
+		return fmt.Errorf("%w: item not found", ErrVersionConflict)
+	}
+	return err
+}
+
+// versions returns the versions a write may replace; nil means any.
+func (p *Precondition) versions() ([]int, error) {
+	if p == nil || p.Any {
+		return nil, nil
+	}
+	if len(p.Versions) == 0 {
+		return nil, ErrVersionConflict
+	}
+	return p.Versions, nil
+}
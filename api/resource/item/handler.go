@@ -1,10 +1,14 @@
 package item
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
 	"production-go-api-template/config"
 	"production-go-api-template/pkg/contextkeys"
+	"production-go-api-template/pkg/jsonpatch"
 	"production-go-api-template/pkg/logger"
 	"production-go-api-template/pkg/router"
 	"production-go-api-template/pkg/validator"
@@ -33,6 +37,7 @@ func CreateItemHandler(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	w.Header().Set(headerETag, ETag(item.Version))
 	router.RespondWithJSON(r, w, http.StatusCreated, ItemResponse{Item: item})
 }
 
@@ -65,6 +70,7 @@ func GetItemHandler(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	w.Header().Set(headerETag, ETag(item.Version))
 	router.RespondWithJSON(r, w, http.StatusOK, ItemResponse{Item: item})
 }
 
@@ -75,20 +81,55 @@ func GetAllItemsHandler(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	items, err := service.GetAllItems(r.Context())
+	query, err := ParseListQuery(r.URL.Query())
+	if err != nil {
+		router.RespondWithError(r, w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	page, err := service.ListItems(r.Context(), query)
 	if err != nil {
 		router.RespondWithError(r, w, http.StatusInternalServerError, "failed to get items", err)
 		return
 	}
 
 	response := ItemsResponse{
-		Items: items,
-		Total: len(items),
+		Items: page.Items,
+		Total: page.Total,
+	}
+	if page.Next != nil {
+		response.NextCursor = page.Next.Encode()
 	}
 
 	router.RespondWithJSON(r, w, http.StatusOK, response)
 }
 
+func SearchItemsHandler(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	service, err := serviceFromRequest(db, r)
+	if err != nil {
+		router.RespondWithError(r, w, http.StatusInternalServerError, "service initialization failed", err)
+		return
+	}
+
+	query, err := ParseSearchQuery(r.URL.Query())
+	if err != nil {
+		router.RespondWithError(r, w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	page, err := service.SearchItems(r.Context(), query)
+	if errors.Is(err, ErrSearchUnavailable) {
+		router.RespondWithError(r, w, http.StatusNotImplemented, "search is not available on this server", err)
+		return
+	}
+	if err != nil {
+		router.RespondWithError(r, w, http.StatusInternalServerError, "failed to search items", err)
+		return
+	}
+
+	router.RespondWithJSON(r, w, http.StatusOK, SearchResponse{Results: page.Results, Total: page.Total})
+}
+
 func UpdateItemHandler(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
 	service, err := serviceFromRequest(db, r)
 	if err != nil {
@@ -114,8 +155,11 @@ func UpdateItemHandler(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	item, err := service.UpdateItem(r.Context(), id, req)
+	item, err := service.UpdateItem(r.Context(), id, req, ParseIfMatch(r.Header))
 	if err != nil {
+		if respondPreconditionError(r, w, err) {
+			return
+		}
 		if strings.Contains(err.Error(), "not found") {
 			router.RespondWithError(r, w, http.StatusNotFound, "item not found", err)
 			return
@@ -124,6 +168,64 @@ func UpdateItemHandler(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	w.Header().Set(headerETag, ETag(item.Version))
+	router.RespondWithJSON(r, w, http.StatusOK, ItemResponse{Item: item})
+}
+
+// PatchItemHandler accepts a JSON merge patch (RFC 7396) or a JSON Patch
+// (RFC 6902) for the editable fields of an item.
+func PatchItemHandler(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	service, err := serviceFromRequest(db, r)
+	if err != nil {
+		router.RespondWithError(r, w, http.StatusInternalServerError, "service initialization failed", err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		router.RespondWithError(r, w, http.StatusBadRequest, "invalid item ID", err)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPatchSize))
+	if err != nil {
+		router.RespondWithError(r, w, http.StatusBadRequest, "invalid input", err)
+		return
+	}
+
+	var patch PatchFunc
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case jsonpatch.MediaTypeMergePatch:
+		patch = func(doc []byte) ([]byte, error) { return jsonpatch.MergePatch(doc, body) }
+	case jsonpatch.MediaTypeJSONPatch:
+		patch = func(doc []byte) ([]byte, error) { return jsonpatch.Apply(doc, body) }
+	default:
+		w.Header().Set("Accept-Patch", jsonpatch.MediaTypeMergePatch+", "+jsonpatch.MediaTypeJSONPatch)
+		router.RespondWithError(r, w, http.StatusUnsupportedMediaType,
+			fmt.Sprintf("Content-Type must be %s or %s", jsonpatch.MediaTypeMergePatch, jsonpatch.MediaTypeJSONPatch), nil)
+		return
+	}
+
+	item, err := service.PatchItem(r.Context(), id, patch, ParseIfMatch(r.Header))
+	if err != nil {
+		if respondPreconditionError(r, w, err) {
+			return
+		}
+		switch {
+		case strings.Contains(err.Error(), "not found") && !errors.Is(err, ErrInvalidPatch):
+			router.RespondWithError(r, w, http.StatusNotFound, "item not found", err)
+		case errors.Is(err, jsonpatch.ErrTestFailed):
+			router.RespondWithError(r, w, http.StatusConflict, err.Error(), nil)
+		case errors.Is(err, ErrInvalidPatch):
+			router.RespondWithError(r, w, http.StatusBadRequest, err.Error(), nil)
+		default:
+			router.RespondWithError(r, w, http.StatusBadRequest, "failed to update item", err)
+		}
+		return
+	}
+
+	w.Header().Set(headerETag, ETag(item.Version))
 	router.RespondWithJSON(r, w, http.StatusOK, ItemResponse{Item: item})
 }
 
@@ -146,8 +248,11 @@ func DeleteItemHandler(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = service.DeleteItem(r.Context(), id)
+	err = service.DeleteItem(r.Context(), id, ParseIfMatch(r.Header))
 	if err != nil {
+		if respondPreconditionError(r, w, err) {
+			return
+		}
 		if strings.Contains(err.Error(), "not found") {
 			router.RespondWithError(r, w, http.StatusNotFound, "item not found", err)
 			return
@@ -159,6 +264,20 @@ func DeleteItemHandler(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// respondPreconditionError answers failed If-Match checks and reports
+// whether err was one.
+func respondPreconditionError(r *http.Request, w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, ErrPreconditionRequired):
+		router.RespondWithError(r, w, http.StatusPreconditionRequired, err.Error(), nil)
+	case errors.Is(err, ErrVersionConflict):
+		router.RespondWithError(r, w, http.StatusPreconditionFailed, err.Error(), nil)
+	default:
+		return false
+	}
+	return true
+}
+
 func serviceFromRequest(db *gorm.DB, r *http.Request) (*Service, error) {
 	cfg, err := validator.ExtractAndValidateContext[*config.Conf](r.Context(), contextkeys.CtxKeyConfig)
 	if err != nil {
@@ -6,12 +6,18 @@ import (
 	"time"
 )
 
+const (
+	ScopeRead  = "items:read"
+	ScopeWrite = "items:write"
+)
+
 type Item struct {
 	ID          int       `json:"id" gorm:"primaryKey;autoIncrement"`
 	Name        string    `json:"name" gorm:"not null;size:255"`
 	Description string    `json:"description" gorm:"size:1000"`
 	Price       float64   `json:"price" gorm:"not null;check:price >= 0"`
 	Category    string    `json:"category" gorm:"not null;size:100"`
+	Version     int       `json:"version" gorm:"not null;default:1"`
 	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 }
@@ -34,9 +40,15 @@ type ItemResponse struct {
 	Item
 }
 
+type SearchResponse struct {
+	Results []SearchResult `json:"results"`
+	Total   int64          `json:"total"`
+}
+
 type ItemsResponse struct {
-	Items []Item `json:"items"`
-	Total int    `json:"total"`
+	Items      []Item `json:"items"`
+	Total      int64  `json:"total"`
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 func (r *CreateItemRequest) Validate() error {
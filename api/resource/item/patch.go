@@ -0,0 +1,67 @@
+package item
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	maxPatchSize     = 1 << 20
+	maxPatchAttempts = 3
+)
+
+// ErrInvalidPatch wraps every error of a patch that cannot be applied or
+// yields a document that is not an item.
+var ErrInvalidPatch = errors.New("invalid patch")
+
+// PatchFunc transforms the JSON form of an item's editable fields, e.g. by
+// applying a merge patch or a JSON Patch to it.
+type PatchFunc func(doc []byte) ([]byte, error)
+
+// requiredFields may be changed but not removed by a patch.
+var requiredFields = []string{"name", "price", "category"}
+
+func applyPatch(current Item, patch PatchFunc) (UpdateItemRequest, error) {
+	doc, err := json.Marshal(UpdateItemRequest{
+		Name:        current.Name,
+		Description: current.Description,
+		Price:       current.Price,
+		Category:    current.Category,
+	})
+	if err != nil {
+		return UpdateItemRequest{}, err
+	}
+
+	patched, err := patch(doc)
+	if err != nil {
+		return UpdateItemRequest{}, fmt.Errorf("%w: %w", ErrInvalidPatch, err)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(patched, &fields); err != nil {
+		return UpdateItemRequest{}, fmt.Errorf("%w: the patched item is not a JSON object", ErrInvalidPatch)
+	}
+	var errs []string
+	for _, name := range requiredFields {
+		if raw, ok := fields[name]; !ok || string(raw) == "null" {
+			errs = append(errs, fmt.Sprintf("%s cannot be removed", name))
+		}
+	}
+	if len(errs) > 0 {
+		return UpdateItemRequest{}, fmt.Errorf("%w: %s", ErrInvalidPatch, strings.Join(errs, "; "))
+	}
+
+	var req UpdateItemRequest
+	dec := json.NewDecoder(bytes.NewReader(patched))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		return UpdateItemRequest{}, fmt.Errorf("%w: %w", ErrInvalidPatch, err)
+	}
+	if err := req.Validate(); err != nil {
+		return UpdateItemRequest{}, fmt.Errorf("%w: %w", ErrInvalidPatch, err)
+	}
+	return req, nil
+}
@@ -0,0 +1,290 @@
+package item
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+
+	defaultSearchSize = 20
+	maxSearchSize     = 100
+
+	sortDescPrefix = "-"
+	defaultSort    = sortDescPrefix + "created_at"
+)
+
+// sortColumn describes a field items may be sorted by. The cursor stores the
+// value of the last item as text, so each column knows how to write and read it.
+type sortColumn struct {
+	column string
+	value  func(Item) string
+	parse  func(string) (any, error)
+}
+
+var sortColumns = map[string]sortColumn{
+	"created_at": {
+		column: "created_at",
+		value:  func(i Item) string { return i.CreatedAt.Format(time.RFC3339Nano) },
+		parse:  parseCursorTime,
+	},
+	"updated_at": {
+		column: "updated_at",
+		value:  func(i Item) string { return i.UpdatedAt.Format(time.RFC3339Nano) },
+		parse:  parseCursorTime,
+	},
+	"name": {
+		column: "name",
+		value:  func(i Item) string { return i.Name },
+		parse:  func(s string) (any, error) { return s, nil },
+	},
+	"price": {
+		column: "price",
+		value:  func(i Item) string { return strconv.FormatFloat(i.Price, 'g', -1, 64) },
+		parse:  func(s string) (any, error) { return strconv.ParseFloat(s, 64) },
+	},
+}
+
+// listParams are the only query parameters GET /api/v1/items accepts.
+var listParams = map[string]struct{}{
+	"limit":        {},
+	"cursor":       {},
+	"sort":         {},
+	"category":     {},
+	"min_price":    {},
+	"max_price":    {},
+	"created_from": {},
+	"created_to":   {},
+}
+
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+func (s Sort) String() string {
+	if s.Desc {
+		return sortDescPrefix + s.Field
+	}
+	return s.Field
+}
+
+// Filter narrows the item list. Prices are inclusive; CreatedFrom is
+// inclusive and CreatedTo exclusive. Zero values do not filter.
+type Filter struct {
+	Category    string
+	MinPrice    *float64
+	MaxPrice    *float64
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+}
+
+// Cursor marks the last item of a page. It records the sort it was issued
+// for, the sort value of that item and its ID, which breaks ties.
+type Cursor struct {
+	Sort  string `json:"sort"`
+	Value string `json:"value"`
+	ID    int    `json:"id"`
+}
+
+func (c Cursor) Encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func DecodeCursor(s string) (Cursor, error) {
+	var c Cursor
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return c, err
+	}
+	if c.ID <= 0 || c.Sort == "" {
+		return c, errors.New("incomplete cursor")
+	}
+	return c, nil
+}
+
+// ListQuery selects one page of items. After is nil for the first page.
+type ListQuery struct {
+	Limit  int
+	After  *Cursor
+	Sort   Sort
+	Filter Filter
+}
+
+// cursorFor returns the cursor that continues the list after item.
+func (q ListQuery) cursorFor(item Item) *Cursor {
+	return &Cursor{Sort: q.Sort.String(), Value: sortColumns[q.Sort.Field].value(item), ID: item.ID}
+}
+
+type ItemPage struct {
+	Items []Item
+	Total int64
+	Next  *Cursor
+}
+
+// ParseListQuery reads the paging, sorting and filter query parameters.
+// Unknown parameters are rejected so typos do not silently return everything.
+func ParseListQuery(query url.Values) (ListQuery, error) {
+	var errs []string
+	q := ListQuery{Limit: defaultPageSize}
+
+	for name := range query {
+		if _, ok := listParams[name]; !ok {
+			errs = append(errs, fmt.Sprintf("unknown query parameter %q", name))
+		}
+	}
+	sort.Strings(errs)
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > maxPageSize {
+			errs = append(errs, fmt.Sprintf("limit must be between 1 and %d", maxPageSize))
+		}
+		q.Limit = limit
+	}
+
+	sortSpec := query.Get("sort")
+	if sortSpec == "" {
+		sortSpec = defaultSort
+	}
+	q.Sort = Sort{Field: strings.TrimPrefix(sortSpec, sortDescPrefix), Desc: strings.HasPrefix(sortSpec, sortDescPrefix)}
+	column, sortable := sortColumns[q.Sort.Field]
+	if !sortable {
+		errs = append(errs, fmt.Sprintf("sort must be one of %s, optionally prefixed with %q", sortFieldList(), sortDescPrefix))
+	}
+
+	if v := query.Get("cursor"); v != "" {
+		cursor, err := DecodeCursor(v)
+		switch {
+		case err != nil:
+			errs = append(errs, "cursor is invalid")
+		case sortable && cursor.Sort != q.Sort.String():
+			errs = append(errs, "cursor was issued for a different sort")
+		case sortable:
+			if _, err := column.parse(cursor.Value); err != nil {
+				errs = append(errs, "cursor is invalid")
+			} else {
+				q.After = &cursor
+			}
+		}
+	}
+
+	q.Filter.Category = query.Get("category")
+	q.Filter.MinPrice = parsePrice(query, "min_price", &errs)
+	q.Filter.MaxPrice = parsePrice(query, "max_price", &errs)
+	if q.Filter.MinPrice != nil && q.Filter.MaxPrice != nil && *q.Filter.MinPrice > *q.Filter.MaxPrice {
+		errs = append(errs, "min_price must not exceed max_price")
+	}
+
+	q.Filter.CreatedFrom = parseTime(query, "created_from", &errs)
+	q.Filter.CreatedTo = parseTime(query, "created_to", &errs)
+	if !q.Filter.CreatedFrom.IsZero() && !q.Filter.CreatedTo.IsZero() && !q.Filter.CreatedFrom.Before(q.Filter.CreatedTo) {
+		errs = append(errs, "created_from must be before created_to")
+	}
+
+	if len(errs) > 0 {
+		return q, errors.New(strings.Join(errs, "; "))
+	}
+
+	return q, nil
+}
+
+type SearchQuery struct {
+	Text  string
+	Limit int
+}
+
+type SearchResult struct {
+	Item
+	Score   float64 `json:"score"`
+	Snippet string  `json:"snippet"`
+}
+
+type SearchPage struct {
+	Results []SearchResult
+	Total   int64
+}
+
+// ParseSearchQuery reads the q and limit query parameters of the search
+// endpoint and, like ParseListQuery, rejects everything else.
+func ParseSearchQuery(query url.Values) (SearchQuery, error) {
+	var errs []string
+	q := SearchQuery{Text: strings.TrimSpace(query.Get("q")), Limit: defaultSearchSize}
+
+	for name := range query {
+		if name != "q" && name != "limit" {
+			errs = append(errs, fmt.Sprintf("unknown query parameter %q", name))
+		}
+	}
+	sort.Strings(errs)
+
+	if q.Text == "" {
+		errs = append(errs, "q is required")
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > maxSearchSize {
+			errs = append(errs, fmt.Sprintf("limit must be between 1 and %d", maxSearchSize))
+		}
+		q.Limit = limit
+	}
+
+	if len(errs) > 0 {
+		return q, errors.New(strings.Join(errs, "; "))
+	}
+
+	return q, nil
+}
+
+func parsePrice(query url.Values, name string, errs *[]string) *float64 {
+	v := query.Get(name)
+	if v == "" {
+		return nil
+	}
+	price, err := strconv.ParseFloat(v, 64)
+	if err != nil || price < 0 || math.IsInf(price, 0) || math.IsNaN(price) {
+		*errs = append(*errs, fmt.Sprintf("%s must be a non-negative number", name))
+		return nil
+	}
+	return &price
+}
+
+func parseTime(query url.Values, name string, errs *[]string) time.Time {
+	v := query.Get(name)
+	if v == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		*errs = append(*errs, fmt.Sprintf("%s must be an RFC 3339 timestamp", name))
+	}
+	return t
+}
+
+func parseCursorTime(s string) (any, error) {
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+func sortFieldList() string {
+	names := make([]string, 0, len(sortColumns))
+	for name := range sortColumns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
@@ -3,6 +3,7 @@ package item
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"gorm.io/gorm"
@@ -11,9 +12,13 @@ import (
 type ItemRepository interface {
 	Create(ctx context.Context, item Item) (Item, error)
 	GetByID(ctx context.Context, id int) (Item, error)
-	GetAll(ctx context.Context) ([]Item, error)
-	Update(ctx context.Context, id int, item Item) (Item, error)
-	Delete(ctx context.Context, id int) error
+	GetPage(ctx context.Context, query ListQuery) (ItemPage, error)
+	Search(ctx context.Context, query SearchQuery) (SearchPage, error)
+	// Update and Delete only touch the item while its version is one of
+	// versions; an empty list skips the check. A mismatch returns
+	// ErrVersionConflict.
+	Update(ctx context.Context, id int, item Item, versions []int) (Item, error)
+	Delete(ctx context.Context, id int, versions []int) error
 }
 
 type sqliteItemRepo struct {
@@ -27,6 +32,7 @@ func NewSQLiteItemRepo(db *gorm.DB) ItemRepository {
 func (r *sqliteItemRepo) Create(ctx context.Context, item Item) (Item, error) {
 	item.CreatedAt = time.Now()
 	item.UpdatedAt = item.CreatedAt
+	item.Version = 1
 
 	if err := r.db.WithContext(ctx).Create(&item).Error; err != nil {
 		return Item{}, err
@@ -47,43 +53,163 @@ func (r *sqliteItemRepo) GetByID(ctx context.Context, id int) (Item, error) {
 	return item, nil
 }
 
-func (r *sqliteItemRepo) GetAll(ctx context.Context) ([]Item, error) {
-	var items []Item
-	if err := r.db.WithContext(ctx).Order("created_at DESC").Find(&items).Error; err != nil {
-		return nil, err
+// GetPage pages through items with a keyset on the sort column and id, so
+// deep pages cost the same as the first one. Total counts every item that
+// matches the filter.
+func (r *sqliteItemRepo) GetPage(ctx context.Context, query ListQuery) (ItemPage, error) {
+	var page ItemPage
+	sortBy, ok := sortColumns[query.Sort.Field]
+	if !ok {
+		return ItemPage{}, fmt.Errorf("cannot sort by %q", query.Sort.Field)
 	}
 
-	return items, nil
-}
+	filtered := applyFilter(r.db.WithContext(ctx).Model(&Item{}), query.Filter)
+	if err := filtered.Count(&page.Total).Error; err != nil {
+		return ItemPage{}, err
+	}
 
-func (r *sqliteItemRepo) Update(ctx context.Context, id int, updatedItem Item) (Item, error) {
-	var existingItem Item
-	if err := r.db.WithContext(ctx).First(&existingItem, id).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return Item{}, errors.New("item not found")
+	direction, compare := "ASC", ">"
+	if query.Sort.Desc {
+		direction, compare = "DESC", "<"
+	}
+
+	q := applyFilter(r.db.WithContext(ctx), query.Filter).
+		Order(sortBy.column + " " + direction).
+		Order("id " + direction).
+		Limit(query.Limit + 1)
+
+	if query.After != nil {
+		value, err := sortBy.parse(query.After.Value)
+		if err != nil {
+			return ItemPage{}, fmt.Errorf("invalid cursor: %w", err)
 		}
-		return Item{}, err
+		q = q.Where(fmt.Sprintf("%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?)", sortBy.column, compare),
+			value, value, query.After.ID)
+	}
+	if err := q.Find(&page.Items).Error; err != nil {
+		return ItemPage{}, err
 	}
 
-	updatedItem.ID = existingItem.ID
-	updatedItem.CreatedAt = existingItem.CreatedAt
-	updatedItem.UpdatedAt = time.Now()
+	if len(page.Items) > query.Limit {
+		page.Items = page.Items[:query.Limit]
+		page.Next = query.cursorFor(page.Items[len(page.Items)-1])
+	}
 
-	if err := r.db.WithContext(ctx).Save(&updatedItem).Error; err != nil {
-		return Item{}, err
+	return page, nil
+}
+
+// Search ranks matches with BM25, weighting name over category over
+// description, and returns the best matching fragment of each item.
+func (r *sqliteItemRepo) Search(ctx context.Context, query SearchQuery) (SearchPage, error) {
+	page := SearchPage{Results: []SearchResult{}}
+	db := r.db.WithContext(ctx)
+	match := matchExpression(query.Text)
+
+	if err := db.Raw("SELECT count(*) FROM items_fts WHERE items_fts MATCH ?", match).
+		Scan(&page.Total).Error; err != nil {
+		return SearchPage{}, searchError(err)
+	}
+
+	err := db.Raw(`SELECT items.*,
+			-bm25(items_fts, 10.0, 1.0, 5.0) AS score,
+			snippet(items_fts, -1, ?, ?, '…', ?) AS snippet
+		FROM items_fts JOIN items ON items.id = items_fts.rowid
+		WHERE items_fts MATCH ?
+		ORDER BY score DESC, items.id DESC
+		LIMIT ?`,
+		snippetOpen, snippetClose, snippetTokens, match, query.Limit).
+		Scan(&page.Results).Error
+	if err != nil {
+		return SearchPage{}, searchError(err)
 	}
 
-	return updatedItem, nil
+	for i := range page.Results {
+		page.Results[i].Snippet = highlight(page.Results[i].Snippet)
+	}
+	return page, nil
 }
 
-func (r *sqliteItemRepo) Delete(ctx context.Context, id int) error {
-	result := r.db.WithContext(ctx).Delete(&Item{}, id)
-	if result.Error != nil {
-		return result.Error
+// applyFilter adds the filter conditions. Times are compared in the local
+// zone because that is how Create stores them in SQLite's text columns.
+func applyFilter(db *gorm.DB, f Filter) *gorm.DB {
+	if f.Category != "" {
+		db = db.Where("category = ?", f.Category)
+	}
+	if f.MinPrice != nil {
+		db = db.Where("price >= ?", *f.MinPrice)
+	}
+	if f.MaxPrice != nil {
+		db = db.Where("price <= ?", *f.MaxPrice)
+	}
+	if !f.CreatedFrom.IsZero() {
+		db = db.Where("created_at >= ?", f.CreatedFrom.Local())
+	}
+	if !f.CreatedTo.IsZero() {
+		db = db.Where("created_at < ?", f.CreatedTo.Local())
+	}
+	return db
+}
+
+// Update checks the version and bumps it in the same UPDATE statement, so two
+// writers holding the same version cannot both succeed.
+func (r *sqliteItemRepo) Update(ctx context.Context, id int, updatedItem Item, versions []int) (Item, error) {
+	var item Item
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		q := tx.Model(&Item{}).Where("id = ?", id)
+		if len(versions) > 0 {
+			q = q.Where("version IN ?", versions)
+		}
+
+		result := q.Updates(map[string]any{
+			"name":        updatedItem.Name,
+			"description": updatedItem.Description,
+			"price":       updatedItem.Price,
+			"category":    updatedItem.Category,
+			"updated_at":  time.Now(),
+			"version":     gorm.Expr("version + 1"),
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return missingOrConflict(tx, id)
+		}
+
+		return tx.First(&item, id).Error
+	})
+	if err != nil {
+		return Item{}, err
 	}
 
-	if result.RowsAffected == 0 {
+	return item, nil
+}
+
+func (r *sqliteItemRepo) Delete(ctx context.Context, id int, versions []int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		q := tx.Where("id = ?", id)
+		if len(versions) > 0 {
+			q = q.Where("version IN ?", versions)
+		}
+
+		result := q.Delete(&Item{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return missingOrConflict(tx, id)
+		}
+		return nil
+	})
+}
+
+// missingOrConflict explains why a conditional write matched no row.
+func missingOrConflict(tx *gorm.DB, id int) error {
+	var count int64
+	if err := tx.Model(&Item{}).Where("id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
 		return errors.New("item not found")
 	}
-	return nil
+	return ErrVersionConflict
 }
@@ -0,0 +1,124 @@
+package item
+
+import (
+	"errors"
+	"html"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	searchTable = "items_fts"
+
+	// The snippet markers are control characters so the text around them can
+	// be HTML-escaped before they are replaced with <mark> tags.
+	snippetOpen   = "\x02"
+	snippetClose  = "\x03"
+	snippetTokens = 12
+)
+
+// ErrSearchUnavailable is returned by the migration and by searches when the
+// SQLite driver was built without FTS5. Everything else keeps working.
+var ErrSearchUnavailable = errors.New("item search needs SQLite FTS5, build with -tags sqlite_fts5")
+
+var searchTriggers = []string{"items_fts_insert", "items_fts_delete", "items_fts_update"}
+
+// searchIndexDDL keeps items_fts in sync with items through triggers, so
+// every write path, including raw SQL, updates the index in the same
+// transaction.
+var searchIndexDDL = []string{
+	`CREATE TRIGGER IF NOT EXISTS items_fts_insert AFTER INSERT ON items BEGIN
+		INSERT INTO items_fts(rowid, name, description, category)
+		VALUES (new.id, new.name, new.description, new.category);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS items_fts_delete AFTER DELETE ON items BEGIN
+		INSERT INTO items_fts(items_fts, rowid, name, description, category)
+		VALUES ('delete', old.id, old.name, old.description, old.category);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS items_fts_update AFTER UPDATE ON items BEGIN
+		INSERT INTO items_fts(items_fts, rowid, name, description, category)
+		VALUES ('delete', old.id, old.name, old.description, old.category);
+		INSERT INTO items_fts(rowid, name, description, category)
+		VALUES (new.id, new.name, new.description, new.category);
+	END`,
+}
+
+// MigrateSearchIndex creates the FTS5 index over the name, description and
+// category of items. A new index is filled from the existing rows.
+//
+// Without FTS5 it drops the sync triggers, which would otherwise fail every
+// write to items, and returns ErrSearchUnavailable. The index is rebuilt
+// once a build with FTS5 finds the triggers missing.
+func MigrateSearchIndex(db *gorm.DB) error {
+	var fts5 bool
+	if err := db.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&fts5).Error; err != nil {
+		return err
+	}
+	if !fts5 {
+		for _, trigger := range searchTriggers {
+			if err := db.Exec("DROP TRIGGER IF EXISTS " + trigger).Error; err != nil {
+				return err
+			}
+		}
+		return ErrSearchUnavailable
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var existing, triggers int64
+		if err := tx.Raw("SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = ?", searchTable).
+			Scan(&existing).Error; err != nil {
+			return err
+		}
+		if err := tx.Raw("SELECT count(*) FROM sqlite_master WHERE type = 'trigger' AND name IN ?", searchTriggers).
+			Scan(&triggers).Error; err != nil {
+			return err
+		}
+
+		if existing == 0 {
+			err := tx.Exec(`CREATE VIRTUAL TABLE items_fts USING fts5(
+				name, description, category,
+				content='items', content_rowid='id', tokenize='unicode61 remove_diacritics 2'
+			)`).Error
+			if err != nil {
+				return err
+			}
+		}
+		if existing == 0 || int(triggers) < len(searchTriggers) {
+			if err := tx.Exec("INSERT INTO items_fts(items_fts) VALUES ('rebuild')").Error; err != nil {
+				return err
+			}
+		}
+
+		for _, ddl := range searchIndexDDL {
+			if err := tx.Exec(ddl).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// searchError reports a missing FTS5 module or index as ErrSearchUnavailable.
+func searchError(err error) error {
+	if msg := err.Error(); strings.Contains(msg, "no such module: fts5") || strings.Contains(msg, "no such table: "+searchTable) {
+		return ErrSearchUnavailable
+	}
+	return err
+}
+
+// matchExpression turns free text into an FTS5 query: every word must match,
+// as a prefix, and FTS5 operators in the input are treated as plain text.
+func matchExpression(text string) string {
+	terms := strings.Fields(text)
+	for i, term := range terms {
+		terms[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"*`
+	}
+	return strings.Join(terms, " ")
+}
+
+// highlight escapes a snippet for HTML and wraps the matched terms in <mark>.
+func highlight(snippet string) string {
+	escaped := html.EscapeString(snippet)
+	return strings.NewReplacer(snippetOpen, "<mark>", snippetClose, "</mark>").Replace(escaped)
+}
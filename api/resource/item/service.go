@@ -2,23 +2,27 @@ package item
 
 import (
 	"context"
+	"errors"
 	"production-go-api-template/config"
 	"production-go-api-template/pkg/logger"
+	"slices"
 
 	"gorm.io/gorm"
 )
 
 type Service struct {
-	DB   *gorm.DB
-	Log  *logger.Logger
-	repo ItemRepository
+	DB             *gorm.DB
+	Log            *logger.Logger
+	repo           ItemRepository
+	requireIfMatch bool
 }
 
 func NewService(cfg *config.Conf, db *gorm.DB, log *logger.Logger) *Service {
 	return &Service{
-		DB:   db,
-		Log:  log,
-		repo: NewSQLiteItemRepo(db),
+		DB:             db,
+		Log:            log,
+		repo:           NewSQLiteItemRepo(db),
+		requireIfMatch: cfg.Items.RequireIfMatch,
 	}
 }
 
@@ -63,21 +67,37 @@ func (s *Service) GetItem(ctx context.Context, id int) (Item, error) {
 	return item, nil
 }
 
-func (s *Service) GetAllItems(ctx context.Context) ([]Item, error) {
+func (s *Service) ListItems(ctx context.Context, query ListQuery) (ItemPage, error) {
 	log := s.Log.WithRequestID(ctx)
-	log.Infof("Fetching all items")
+	log.Infof("Fetching up to %d items", query.Limit)
 
-	items, err := s.repo.GetAll(ctx)
+	page, err := s.repo.GetPage(ctx, query)
 	if err != nil {
-		log.Errorf("failed to get all items: %v", err)
-		return nil, err
+		log.Errorf("failed to list items: %v", err)
+		return ItemPage{}, err
 	}
 
-	log.Infof("Successfully retrieved %d items", len(items))
-	return items, nil
+	log.Infof("Successfully retrieved %d of %d items", len(page.Items), page.Total)
+	return page, nil
 }
 
-func (s *Service) UpdateItem(ctx context.Context, id int, req UpdateItemRequest) (Item, error) {
+func (s *Service) SearchItems(ctx context.Context, query SearchQuery) (SearchPage, error) {
+	log := s.Log.WithRequestID(ctx)
+	log.Infof("Searching items for %q", query.Text)
+
+	page, err := s.repo.Search(ctx, query)
+	if err != nil {
+		log.Errorf("failed to search items: %v", err)
+		return SearchPage{}, err
+	}
+
+	log.Infof("Search matched %d items", page.Total)
+	return page, nil
+}
+
+// UpdateItem replaces the editable fields. With a precondition the item is
+// only written while it is at one of the versions the client has seen.
+func (s *Service) UpdateItem(ctx context.Context, id int, req UpdateItemRequest, pre *Precondition) (Item, error) {
 	log := s.Log.WithRequestID(ctx)
 
 	if err := req.Validate(); err != nil {
@@ -85,6 +105,57 @@ func (s *Service) UpdateItem(ctx context.Context, id int, req UpdateItemRequest)
 		return Item{}, err
 	}
 
+	versions, err := s.checkPrecondition(pre)
+	if err != nil {
+		log.Errorf("precondition failed for update item %d: %v", id, err)
+		return Item{}, err
+	}
+
+	item, err := s.update(ctx, id, req, versions)
+	return item, pre.missing(err)
+}
+
+// PatchItem applies patch to the editable fields of the stored item and
+// validates the result like a full update. The write is conditional on the
+// version the patch was applied to; without If-Match a concurrent change
+// makes it re-read the item and apply the patch again.
+func (s *Service) PatchItem(ctx context.Context, id int, patch PatchFunc, pre *Precondition) (Item, error) {
+	log := s.Log.WithRequestID(ctx)
+	log.Infof("Patching item with ID: %d", id)
+
+	versions, err := s.checkPrecondition(pre)
+	if err != nil {
+		log.Errorf("precondition failed for patch item %d: %v", id, err)
+		return Item{}, err
+	}
+
+	for attempt := 1; ; attempt++ {
+		current, err := s.repo.GetByID(ctx, id)
+		if err != nil {
+			log.Errorf("failed to get item with ID %d: %v", id, err)
+			return Item{}, pre.missing(err)
+		}
+		if versions != nil && !slices.Contains(versions, current.Version) {
+			log.Errorf("precondition failed for patch item %d: at version %d", id, current.Version)
+			return Item{}, ErrVersionConflict
+		}
+
+		req, err := applyPatch(current, patch)
+		if err != nil {
+			log.Errorf("failed to patch item with ID %d: %v", id, err)
+			return Item{}, err
+		}
+
+		updated, err := s.update(ctx, id, req, []int{current.Version})
+		if errors.Is(err, ErrVersionConflict) && versions == nil && attempt < maxPatchAttempts {
+			continue
+		}
+		return updated, pre.missing(err)
+	}
+}
+
+func (s *Service) update(ctx context.Context, id int, req UpdateItemRequest, versions []int) (Item, error) {
+	log := s.Log.WithRequestID(ctx)
 	log.Infof("Updating item with ID: %d", id)
 
 	item := Item{
@@ -94,25 +165,38 @@ func (s *Service) UpdateItem(ctx context.Context, id int, req UpdateItemRequest)
 		Category:    req.Category,
 	}
 
-	updatedItem, err := s.repo.Update(ctx, id, item)
+	updatedItem, err := s.repo.Update(ctx, id, item, versions)
 	if err != nil {
 		log.Errorf("failed to update item with ID %d: %v", id, err)
 		return Item{}, err
 	}
 
-	log.Infof("Successfully updated item with ID: %d", updatedItem.ID)
+	log.Infof("Successfully updated item with ID: %d to version %d", updatedItem.ID, updatedItem.Version)
 	return updatedItem, nil
 }
 
-func (s *Service) DeleteItem(ctx context.Context, id int) error {
+func (s *Service) DeleteItem(ctx context.Context, id int, pre *Precondition) error {
 	log := s.Log.WithRequestID(ctx)
 	log.Infof("Deleting item with ID: %d", id)
 
-	err := s.repo.Delete(ctx, id)
+	versions, err := s.checkPrecondition(pre)
 	if err != nil {
-		log.Errorf("failed to delete item with ID %d: %v", id, err)
+		log.Errorf("precondition failed for delete item %d: %v", id, err)
 		return err
 	}
+
+	err = s.repo.Delete(ctx, id, versions)
+	if err != nil {
+		log.Errorf("failed to delete item with ID %d: %v", id, err)
+		return pre.missing(err)
+	}
 	log.Infof("Successfully deleted item with ID: %d", id)
 	return nil
 }
+
+func (s *Service) checkPrecondition(pre *Precondition) ([]int, error) {
+	if pre == nil && s.requireIfMatch {
+		return nil, ErrPreconditionRequired
+	}
+	return pre.versions()
+}
@@ -7,7 +7,11 @@ import (
 )
 
 func AutoMigrateAll(db *gorm.DB) error {
-	return db.AutoMigrate(
+	if err := db.AutoMigrate(
 		&item.Item{},
-	)
+	); err != nil {
+		return err
+	}
+
+	return item.MigrateSearchIndex(db)
 }
@@ -0,0 +1,111 @@
+package oauth
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"production-go-api-template/pkg/auth"
+	"production-go-api-template/pkg/constants"
+	"production-go-api-template/pkg/router"
+	"time"
+)
+
+func TokenHandler(s *Service, w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		respondWithOAuthError(r, w, http.StatusBadRequest, ErrCodeInvalidRequest, "malformed form body")
+		return
+	}
+
+	client, ok := authenticateClient(s, w, r)
+	if !ok {
+		return
+	}
+
+	if r.PostForm.Get("grant_type") != GrantTypeClientCredentials {
+		respondWithOAuthError(r, w, http.StatusBadRequest, ErrCodeUnsupportedGrantType, "only client_credentials is supported")
+		return
+	}
+
+	resp, err := s.IssueToken(r.Context(), client, r.PostForm.Get("scope"))
+	if err != nil {
+		if errors.Is(err, ErrInvalidScope) {
+			respondWithOAuthError(r, w, http.StatusBadRequest, ErrCodeInvalidScope, err.Error())
+			return
+		}
+		respondWithOAuthError(r, w, http.StatusInternalServerError, ErrCodeServerError, "failed to issue token")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	router.RespondWithJSON(r, w, http.StatusOK, resp)
+}
+
+func IntrospectHandler(s *Service, w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		respondWithOAuthError(r, w, http.StatusBadRequest, ErrCodeInvalidRequest, "malformed form body")
+		return
+	}
+
+	client, ok := authenticateClient(s, w, r)
+	if !ok {
+		return
+	}
+
+	token := r.PostForm.Get("token")
+	if token == constants.EmptyString {
+		respondWithOAuthError(r, w, http.StatusBadRequest, ErrCodeInvalidRequest, "token is required")
+		return
+	}
+
+	router.RespondWithJSON(r, w, http.StatusOK, s.Introspect(r.Context(), client, token))
+}
+
+func RevokeHandler(s *Service, w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		respondWithOAuthError(r, w, http.StatusBadRequest, ErrCodeInvalidRequest, "malformed form body")
+		return
+	}
+
+	client, ok := authenticateClient(s, w, r)
+	if !ok {
+		return
+	}
+
+	token := r.PostForm.Get("token")
+	if token == constants.EmptyString {
+		respondWithOAuthError(r, w, http.StatusBadRequest, ErrCodeInvalidRequest, "token is required")
+		return
+	}
+
+	if err := s.Revoke(r.Context(), client, token); err != nil {
+		respondWithOAuthError(r, w, http.StatusServiceUnavailable, ErrCodeServerError, "failed to revoke token")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func authenticateClient(s *Service, w http.ResponseWriter, r *http.Request) (auth.Client, bool) {
+	id, secret, ok := r.BasicAuth()
+	if ok {
+		id, _ = url.QueryUnescape(id)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+
+	client, ok := s.Clients.Authenticate(id, secret, time.Now())
+	if !ok {
+		s.RecordFailure(r, id, "invalid client credentials")
+		s.Log.WithRequestID(r.Context()).Warnf("OAuth client authentication failed for client %q", id)
+		w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+		respondWithOAuthError(r, w, http.StatusUnauthorized, ErrCodeInvalidClient, "client authentication failed")
+		return auth.Client{}, false
+	}
+	return client, true
+}
+
+func respondWithOAuthError(r *http.Request, w http.ResponseWriter, code int, errCode, description string) {
+	w.Header().Set("Cache-Control", "no-store")
+	router.RespondWithJSON(r, w, code, ErrorResponse{Error: errCode, Description: description})
+}
@@ -0,0 +1,41 @@
+package oauth
+
+import (
+	"sync"
+	"time"
+)
+
+type RevocationStore interface {
+	Revoke(jti string, until time.Time) error
+	IsRevoked(jti string) (bool, error)
+}
+
+type memoryRevocationStore struct {
+	mu      sync.Mutex
+	revoked map[string]time.Time
+}
+
+func NewMemoryRevocationStore() RevocationStore {
+	return &memoryRevocationStore{revoked: make(map[string]time.Time)}
+}
+
+func (s *memoryRevocationStore) Revoke(jti string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for id, exp := range s.revoked {
+		if !now.Before(exp) {
+			delete(s.revoked, id)
+		}
+	}
+	s.revoked[jti] = until
+	return nil
+}
+
+func (s *memoryRevocationStore) IsRevoked(jti string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	until, ok := s.revoked[jti]
+	return ok && time.Now().Before(until), nil
+}
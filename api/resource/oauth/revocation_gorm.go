@@ -0,0 +1,44 @@
+package oauth
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RevokedToken struct {
+	JTI       string    `gorm:"primaryKey;size:64"`
+	ExpiresAt time.Time `gorm:"index"`
+}
+
+// gormRevocationStore keeps revocations across restarts and shares them
+// between replicas using the same database. Times are stored in UTC because
+// SQLite compares them as text.
+type gormRevocationStore struct {
+	db *gorm.DB
+}
+
+func NewGormRevocationStore(db *gorm.DB) (RevocationStore, error) {
+	if err := db.AutoMigrate(&RevokedToken{}); err != nil {
+		return nil, err
+	}
+	return &gormRevocationStore{db: db}, nil
+}
+
+func (s *gormRevocationStore) Revoke(jti string, until time.Time) error {
+	now := time.Now().UTC()
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("expires_at <= ?", now).Delete(&RevokedToken{}).Error; err != nil {
+			return err
+		}
+		row := RevokedToken{JTI: jti, ExpiresAt: until.UTC()}
+		return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&row).Error
+	})
+}
+
+func (s *gormRevocationStore) IsRevoked(jti string) (bool, error) {
+	var count int64
+	err := s.db.Model(&RevokedToken{}).Where("jti = ? AND expires_at > ?", jti, time.Now().UTC()).Count(&count).Error
+	return count > 0, err
+}
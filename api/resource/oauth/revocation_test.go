@@ -0,0 +1,105 @@
+package oauth
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+func openTestDB(t *testing.T, path string) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{Logger: gormlogger.Default.LogMode(gormlogger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func newGormTestStore(t *testing.T, path string) RevocationStore {
+	t.Helper()
+	store, err := NewGormRevocationStore(openTestDB(t, path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestRevocationStores(t *testing.T) {
+	stores := map[string]func(t *testing.T) RevocationStore{
+		"memory": func(*testing.T) RevocationStore { return NewMemoryRevocationStore() },
+		"gorm": func(t *testing.T) RevocationStore {
+			return newGormTestStore(t, filepath.Join(t.TempDir(), "revocations.sqlite"))
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			s := newStore(t)
+			now := time.Now()
+
+			for jti, until := range map[string]time.Time{
+				"live":    now.Add(time.Hour),
+				"expired": now.Add(-time.Second),
+				"renewed": now.Add(-time.Second),
+			} {
+				if err := s.Revoke(jti, until); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := s.Revoke("renewed", now.Add(time.Hour)); err != nil {
+				t.Fatal(err)
+			}
+
+			for jti, want := range map[string]bool{"live": true, "expired": false, "renewed": true, "unknown": false} {
+				got, err := s.IsRevoked(jti)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != want {
+					t.Errorf("IsRevoked(%q) = %v, want %v", jti, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGormRevocationStoreSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "revocations.sqlite")
+	if err := newGormTestStore(t, path).Revoke("jti-1", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	revoked, err := newGormTestStore(t, path).IsRevoked("jti-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !revoked {
+		t.Error("revocation was lost when the store was reopened")
+	}
+}
+
+func TestGormRevocationStorePrunesExpired(t *testing.T) {
+	db := openTestDB(t, filepath.Join(t.TempDir(), "revocations.sqlite"))
+	store, err := NewGormRevocationStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Revoke("old", time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Revoke("new", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	var count int64
+	if err := db.Model(&RevokedToken{}).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("%d rows stored, want the expired revocation pruned", count)
+	}
+}
@@ -0,0 +1,195 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"production-go-api-template/config"
+	"production-go-api-template/pkg/auth"
+	"production-go-api-template/pkg/constants"
+	"production-go-api-template/pkg/logger"
+	"strings"
+	"time"
+)
+
+const (
+	signingKeyID = "oauth"
+	jtiLength    = 16
+)
+
+var (
+	ErrInvalidScope   = errors.New("requested scope exceeds the scopes granted to the client")
+	ErrTokenRevoked   = errors.New("token revoked")
+	ErrClientInactive = errors.New("token client is unknown or disabled")
+)
+
+type FailureRecorder interface {
+	RecordFailure(r *http.Request, clientID, detail string)
+}
+
+type Service struct {
+	Clients  *auth.Registry
+	Log      *logger.Logger
+	failures FailureRecorder
+	revoked  RevocationStore
+	verifier *auth.JWTVerifier
+	secret   string
+	issuer   string
+	audience string
+	ttl      time.Duration
+}
+
+func NewService(cfg config.ConfOAuth, clients *auth.Registry, failures FailureRecorder, log *logger.Logger) *Service {
+	key := auth.NewHMACKey(signingKeyID, cfg.SigningSecret)
+	return &Service{
+		Clients:  clients,
+		Log:      log,
+		failures: failures,
+		revoked:  NewMemoryRevocationStore(),
+		verifier: auth.NewJWTVerifier([]auth.VerificationKey{key}, cfg.Issuer, cfg.Audience, 0),
+		secret:   cfg.SigningSecret,
+		issuer:   cfg.Issuer,
+		audience: cfg.Audience,
+		ttl:      cfg.TokenTTL,
+	}
+}
+
+func (s *Service) WithRevocationStore(store RevocationStore) *Service {
+	s.revoked = store
+	return s
+}
+
+func (s *Service) IssueToken(ctx context.Context, client auth.Client, requestedScope string) (TokenResponse, error) {
+	log := s.Log.WithRequestID(ctx)
+
+	scopes, err := grantedScopes(client.GrantedScopes(), strings.Fields(requestedScope))
+	if err != nil {
+		log.Warnf("client %s requested scope %q beyond its grant", client.ID, requestedScope)
+		return TokenResponse{}, err
+	}
+
+	jti, err := newTokenID()
+	if err != nil {
+		return TokenResponse{}, err
+	}
+
+	now := time.Now()
+	scope := strings.Join(scopes, " ")
+	claims := map[string]any{
+		"iss":       s.issuer,
+		"sub":       client.ID,
+		"aud":       s.audience,
+		"iat":       now.Unix(),
+		"nbf":       now.Unix(),
+		"exp":       now.Add(s.ttl).Unix(),
+		"jti":       jti,
+		"client_id": client.ID,
+	}
+	if scope != constants.EmptyString {
+		claims["scope"] = scope
+	}
+
+	token, err := auth.SignHS256(claims, signingKeyID, s.secret)
+	if err != nil {
+		return TokenResponse{}, err
+	}
+
+	log.Infof("Issued access token %s to client %s", jti, client.ID)
+	return TokenResponse{
+		AccessToken: token,
+		TokenType:   TokenTypeBearer,
+		ExpiresIn:   int64(s.ttl / time.Second),
+		Scope:       scope,
+	}, nil
+}
+
+// Verify checks a token issued by this server, including revocation and that
+// its client is still enabled. It lets the authentication middleware accept
+// these tokens on protected routes.
+func (s *Service) Verify(token string, now time.Time) (auth.Claims, error) {
+	claims, err := s.verifier.Verify(token, now)
+	if err != nil {
+		return auth.Claims{}, err
+	}
+
+	client, ok := s.Clients.Get(claims.Subject)
+	if !ok || !client.Enabled {
+		return auth.Claims{}, ErrClientInactive
+	}
+
+	revoked, err := s.revoked.IsRevoked(claims.ID)
+	if err != nil {
+		return auth.Claims{}, err
+	}
+	if revoked {
+		return auth.Claims{}, ErrTokenRevoked
+	}
+	return claims, nil
+}
+
+func (s *Service) Introspect(ctx context.Context, caller auth.Client, token string) IntrospectionResponse {
+	claims, err := s.Verify(token, time.Now())
+	if err != nil || !canManage(caller, claims) {
+		return IntrospectionResponse{Active: false}
+	}
+
+	s.Log.WithRequestID(ctx).Infof("Client %s introspected token %s", caller.ID, claims.ID)
+	return IntrospectionResponse{
+		Active:    true,
+		Scope:     strings.Join(claims.Scopes, " "),
+		ClientID:  claims.Subject,
+		Subject:   claims.Subject,
+		TokenType: TokenTypeBearer,
+		ExpiresAt: claims.ExpiresAt.Unix(),
+		IssuedAt:  claims.IssuedAt.Unix(),
+		Issuer:    claims.Issuer,
+		Audience:  claims.Audience,
+		JTI:       claims.ID,
+	}
+}
+
+// Revoke follows RFC 7009: unknown, expired or foreign tokens are ignored so
+// the response does not reveal whether a token was valid.
+func (s *Service) Revoke(ctx context.Context, caller auth.Client, token string) error {
+	claims, err := s.verifier.Verify(token, time.Now())
+	if err != nil || !canManage(caller, claims) {
+		return nil
+	}
+
+	if err := s.revoked.Revoke(claims.ID, claims.ExpiresAt); err != nil {
+		return err
+	}
+	s.Log.WithRequestID(ctx).Infof("Client %s revoked token %s", caller.ID, claims.ID)
+	return nil
+}
+
+func (s *Service) RecordFailure(r *http.Request, clientID, detail string) {
+	if s.failures != nil {
+		s.failures.RecordFailure(r, clientID, detail)
+	}
+}
+
+func canManage(caller auth.Client, claims auth.Claims) bool {
+	return caller.Admin || caller.ID == claims.Subject
+}
+
+func grantedScopes(allowed, requested []string) ([]string, error) {
+	if len(requested) == constants.ZeroIndex {
+		return allowed, nil
+	}
+
+	if len(auth.MissingScopes(allowed, requested)) > constants.ZeroIndex {
+		return nil, ErrInvalidScope
+	}
+	return requested, nil
+}
+
+func newTokenID() (string, error) {
+	b := make([]byte, jtiLength)
+	if _, err := rand.Read(b); err != nil {
+		return constants.EmptyString, err
+	}
+	return hex.EncodeToString(b), nil
+}
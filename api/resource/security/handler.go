@@ -0,0 +1,77 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"production-go-api-template/api/router/middleware"
+	"production-go-api-template/pkg/router"
+	"production-go-api-template/pkg/validator"
+	"time"
+)
+
+type BlockManager interface {
+	ListBlocks(ctx context.Context) ([]middleware.BlockEntry, error)
+	Block(ctx context.Context, target, reason string, duration time.Duration) (middleware.BlockEntry, error)
+	Unblock(ctx context.Context, target string) (bool, error)
+}
+
+func ListBlocksHandler(m BlockManager, w http.ResponseWriter, r *http.Request) {
+	blocks, err := m.ListBlocks(r.Context())
+	if err != nil {
+		router.RespondWithError(r, w, http.StatusInternalServerError, "failed to list blocks", err)
+		return
+	}
+
+	router.RespondWithJSON(r, w, http.StatusOK, BlocksResponse{Blocks: blocks, Total: len(blocks)})
+}
+
+func CreateBlockHandler(m BlockManager, w http.ResponseWriter, r *http.Request) {
+	var req CreateBlockRequest
+	if err := validator.DecodeAndValidate(r, &req); err != nil {
+		router.RespondWithError(r, w, http.StatusBadRequest, "invalid input", err)
+		return
+	}
+
+	block, err := m.Block(r.Context(), req.Target, req.Reason, req.ParsedDuration())
+	if err != nil {
+		if isTargetError(err) {
+			router.RespondWithError(r, w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+		router.RespondWithError(r, w, http.StatusInternalServerError, "failed to create block", err)
+		return
+	}
+
+	router.RespondWithJSON(r, w, http.StatusCreated, BlockResponse{BlockEntry: block})
+}
+
+func DeleteBlockHandler(m BlockManager, w http.ResponseWriter, r *http.Request) {
+	target := r.PathValue("target")
+	if target == "" {
+		router.RespondWithError(r, w, http.StatusBadRequest, "target is required", nil)
+		return
+	}
+
+	removed, err := m.Unblock(r.Context(), target)
+	if err != nil {
+		if isTargetError(err) {
+			router.RespondWithError(r, w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+		router.RespondWithError(r, w, http.StatusInternalServerError, "failed to remove block", err)
+		return
+	}
+	if !removed {
+		router.RespondWithError(r, w, http.StatusNotFound, "block not found", nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func isTargetError(err error) bool {
+	return errors.Is(err, middleware.ErrInvalidBlockTarget) ||
+		errors.Is(err, middleware.ErrSubnetPrefix) ||
+		errors.Is(err, middleware.ErrSubnetBlockDisabled)
+}
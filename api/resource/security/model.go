@@ -0,0 +1,52 @@
+package security
+
+import (
+	"errors"
+	"production-go-api-template/api/router/middleware"
+	"strings"
+	"time"
+)
+
+type CreateBlockRequest struct {
+	Target   string `json:"target"`
+	Reason   string `json:"reason"`
+	Duration string `json:"duration"`
+}
+
+type BlockResponse struct {
+	middleware.BlockEntry
+}
+
+type BlocksResponse struct {
+	Blocks []middleware.BlockEntry `json:"blocks"`
+	Total  int                     `json:"total"`
+}
+
+func (r *CreateBlockRequest) Validate() error {
+	var errs []string
+
+	if strings.TrimSpace(r.Target) == "" {
+		errs = append(errs, "target is required")
+	}
+
+	if strings.TrimSpace(r.Reason) == "" {
+		errs = append(errs, "reason is required")
+	}
+
+	if r.Duration != "" {
+		if d, err := time.ParseDuration(r.Duration); err != nil || d <= 0 {
+			errs = append(errs, "duration must be a positive duration such as 30m or 24h")
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+func (r *CreateBlockRequest) ParsedDuration() time.Duration {
+	d, _ := time.ParseDuration(r.Duration)
+	return d
+}
@@ -0,0 +1,31 @@
+package router
+
+import (
+	"net/http"
+	"production-go-api-template/api/resource/audit"
+)
+
+type AuditHandler struct {
+	Events audit.EventQuerier
+}
+
+func NewAuditHandler(events audit.EventQuerier) *AuditHandler {
+	return &AuditHandler{Events: events}
+}
+
+func (h *AuditHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /events", h.ListEventsHandler)
+}
+
+func (h *AuditHandler) ListEventsHandler(w http.ResponseWriter, r *http.Request) {
+	audit.ListEventsHandler(h.Events, w, r)
+}
+
+func SetupAuditRouter(events audit.EventQuerier) *http.ServeMux {
+	auditRouter := http.NewServeMux()
+
+	h := NewAuditHandler(events)
+	h.RegisterRoutes(auditRouter)
+
+	return auditRouter
+}
@@ -3,6 +3,7 @@ package router
 import (
 	"net/http"
 	"production-go-api-template/api/resource/item"
+	"production-go-api-template/api/router/middleware"
 
 	"gorm.io/gorm"
 )
@@ -16,11 +17,16 @@ func NewItemHandler(db *gorm.DB) *ItemHandler {
 }
 
 func (h *ItemHandler) RegisterRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("POST /", h.CreateItemHandler)
-	mux.HandleFunc("GET /", h.GetAllItemsHandler)
-	mux.HandleFunc("GET /{id}", h.GetItemHandler)
-	mux.HandleFunc("PUT /{id}", h.UpdateItemHandler)
-	mux.HandleFunc("DELETE /{id}", h.DeleteItemHandler)
+	read := middleware.RequireScopes(item.ScopeRead)
+	write := middleware.RequireScopes(item.ScopeWrite)
+
+	mux.Handle("POST /", write(http.HandlerFunc(h.CreateItemHandler)))
+	mux.Handle("GET /", read(http.HandlerFunc(h.GetAllItemsHandler)))
+	mux.Handle("GET /search", read(http.HandlerFunc(h.SearchItemsHandler)))
+	mux.Handle("GET /{id}", read(http.HandlerFunc(h.GetItemHandler)))
+	mux.Handle("PUT /{id}", write(http.HandlerFunc(h.UpdateItemHandler)))
+	mux.Handle("PATCH /{id}", write(http.HandlerFunc(h.PatchItemHandler)))
+	mux.Handle("DELETE /{id}", write(http.HandlerFunc(h.DeleteItemHandler)))
 }
 
 func (h *ItemHandler) CreateItemHandler(w http.ResponseWriter, r *http.Request) {
@@ -35,10 +41,18 @@ func (h *ItemHandler) GetItemHandler(w http.ResponseWriter, r *http.Request) {
 	item.GetItemHandler(h.DB, w, r)
 }
 
+func (h *ItemHandler) SearchItemsHandler(w http.ResponseWriter, r *http.Request) {
+	item.SearchItemsHandler(h.DB, w, r)
+}
+
 func (h *ItemHandler) UpdateItemHandler(w http.ResponseWriter, r *http.Request) {
 	item.UpdateItemHandler(h.DB, w, r)
 }
 
+func (h *ItemHandler) PatchItemHandler(w http.ResponseWriter, r *http.Request) {
+	item.PatchItemHandler(h.DB, w, r)
+}
+
 func (h *ItemHandler) DeleteItemHandler(w http.ResponseWriter, r *http.Request) {
 	item.DeleteItemHandler(h.DB, w, r)
 }
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"net"
+	"production-go-api-template/pkg/clientip"
+	"sync"
+)
+
+type IPAccessList struct {
+	mu    sync.RWMutex
+	allow []*net.IPNet
+	deny  []*net.IPNet
+}
+
+func NewIPAccessList(allow, deny []string) (*IPAccessList, error) {
+	l := &IPAccessList{}
+	if err := l.Update(allow, deny); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *IPAccessList) Update(allow, deny []string) error {
+	allowNets, err := clientip.ParseCIDRs(allow)
+	if err != nil {
+		return err
+	}
+	denyNets, err := clientip.ParseCIDRs(deny)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.allow = allowNets
+	l.deny = denyNets
+	return nil
+}
+
+func (l *IPAccessList) Allowed(ipStr string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return containsIP(l.allow, ipStr)
+}
+
+func (l *IPAccessList) Denied(ipStr string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return containsIP(l.deny, ipStr)
+}
+
+func (l *IPAccessList) Sizes() (int, int) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.allow), len(l.deny)
+}
+
+func containsIP(nets []*net.IPNet, ipStr string) bool {
+	ip := net.ParseIP(ipStr)
+	if ip == nil {
+		return false
+	}
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
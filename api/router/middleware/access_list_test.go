@@ -0,0 +1,55 @@
+package middleware
+
+import "testing"
+
+func TestIPAccessList(t *testing.T) {
+	list := mustAccessList(t, []string{"10.0.0.0/8", "2001:db8::/32"}, []string{"203.0.113.7"})
+
+	tests := []struct {
+		ip      string
+		allowed bool
+		denied  bool
+	}{
+		{"10.1.2.3", true, false},
+		{"11.0.0.1", false, false},
+		{"2001:db8::1", true, false},
+		{"2001:db9::1", false, false},
+		{"203.0.113.7", false, true},
+		{"203.0.113.8", false, false},
+		{"not an ip", false, false},
+		{"", false, false},
+	}
+
+	for _, tt := range tests {
+		if got := list.Allowed(tt.ip); got != tt.allowed {
+			t.Errorf("Allowed(%q) = %v, want %v", tt.ip, got, tt.allowed)
+		}
+		if got := list.Denied(tt.ip); got != tt.denied {
+			t.Errorf("Denied(%q) = %v, want %v", tt.ip, got, tt.denied)
+		}
+	}
+}
+
+func TestIPAccessListUpdate(t *testing.T) {
+	list := mustAccessList(t, []string{"10.0.0.0/8"}, []string{"203.0.113.0/24", "198.51.100.7"})
+	if allowed, denied := list.Sizes(); allowed != 1 || denied != 2 {
+		t.Fatalf("Sizes() = %d, %d, want 1, 2", allowed, denied)
+	}
+
+	if err := list.Update([]string{"192.0.2.0/24", "", "2001:db8::1"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if allowed, denied := list.Sizes(); allowed != 2 || denied != 0 {
+		t.Errorf("Sizes() after update = %d, %d, want 2, 0", allowed, denied)
+	}
+	if list.Allowed("10.1.2.3") || !list.Allowed("192.0.2.1") || list.Denied("203.0.113.1") {
+		t.Error("Update() did not replace both lists")
+	}
+
+	if err := list.Update([]string{"10.0.0.0/8"}, []string{"203.0.113.0/33"}); err == nil {
+		t.Fatal("Update() expected an error for an invalid CIDR")
+	}
+	if allowed, denied := list.Sizes(); allowed != 2 || denied != 0 || !list.Allowed("192.0.2.1") {
+		t.Errorf("failed Update() changed the lists: Sizes() = %d, %d", allowed, denied)
+	}
+}
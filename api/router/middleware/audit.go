@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"production-go-api-template/pkg/audit"
+	"production-go-api-template/pkg/constants"
+	"production-go-api-template/pkg/contextkeys"
+	"strings"
+	"time"
+)
+
+type Auditor interface {
+	Record(e audit.Event)
+}
+
+type nopAuditor struct{}
+
+func (nopAuditor) Record(audit.Event) {}
+
+// requestPath returns the path as sent by the client. Unlike r.URL.Path it is
+// not shortened by handlers mounted under a prefix.
+func requestPath(r *http.Request) string {
+	path, _, _ := strings.Cut(r.RequestURI, "?")
+	if path == constants.EmptyString {
+		return r.URL.Path
+	}
+	return path
+}
+
+func (a *Authenticator) audit(r *http.Request, event audit.EventType, clientID, detail string) {
+	a.auditor.Record(audit.Event{
+		Time:      time.Now().UTC(),
+		Type:      event,
+		ClientID:  clientID,
+		IP:        clientIP(r),
+		Method:    r.Method,
+		Route:     requestPath(r),
+		RequestID: contextkeys.GetRequestID(r.Context()),
+		Detail:    detail,
+	})
+}
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"fmt"
+	"production-go-api-template/pkg/constants"
+	"sort"
+	"strings"
+)
+
+type AuthScheme string
+
+const (
+	SchemeSignature AuthScheme = "signature"
+	SchemeJWT       AuthScheme = "jwt"
+	SchemeMTLS      AuthScheme = "mtls"
+
+	routeSchemeAssign    = "="
+	routeSchemeSeparator = ","
+)
+
+type schemeRoute struct {
+	prefix  string
+	schemes []AuthScheme
+}
+
+// SchemeTable maps route group prefixes to the authentication schemes they
+// accept. The longest matching prefix wins; unmatched paths use the defaults.
+type SchemeTable struct {
+	defaults []AuthScheme
+	routes   []schemeRoute
+}
+
+// ParseSchemeTable reads defaults such as "signature,jwt" and route entries
+// such as "/api/v1/items=signature,jwt".
+func ParseSchemeTable(defaults string, routes []string) (SchemeTable, error) {
+	var table SchemeTable
+
+	schemes, err := parseSchemes(defaults)
+	if err != nil {
+		return table, err
+	}
+	table.defaults = schemes
+
+	for _, entry := range routes {
+		entry = strings.TrimSpace(entry)
+		if entry == constants.EmptyString {
+			continue
+		}
+		prefix, list, ok := strings.Cut(entry, routeSchemeAssign)
+		if !ok || !strings.HasPrefix(prefix, "/") {
+			return table, fmt.Errorf("invalid route scheme entry %q", entry)
+		}
+		schemes, err := parseSchemes(list)
+		if err != nil {
+			return table, err
+		}
+		table.routes = append(table.routes, schemeRoute{prefix: strings.TrimRight(prefix, "/"), schemes: schemes})
+	}
+
+	sort.Slice(table.routes, func(i, j int) bool {
+		return len(table.routes[i].prefix) > len(table.routes[j].prefix)
+	})
+	return table, nil
+}
+
+func DefaultSchemeTable() SchemeTable {
+	return SchemeTable{defaults: []AuthScheme{SchemeSignature}}
+}
+
+func (t SchemeTable) ForPath(path string) []AuthScheme {
+	for _, route := range t.routes {
+		if path == route.prefix || strings.HasPrefix(path, route.prefix+"/") {
+			return route.schemes
+		}
+	}
+	return t.defaults
+}
+
+func (t SchemeTable) Uses(scheme AuthScheme) bool {
+	if allows(t.defaults, scheme) {
+		return true
+	}
+	for _, route := range t.routes {
+		if allows(route.schemes, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseSchemes(list string) ([]AuthScheme, error) {
+	var schemes []AuthScheme
+	for _, name := range strings.Split(list, routeSchemeSeparator) {
+		switch scheme := AuthScheme(strings.TrimSpace(name)); scheme {
+		case SchemeSignature, SchemeJWT, SchemeMTLS:
+			schemes = append(schemes, scheme)
+		default:
+			return nil, fmt.Errorf("unknown authentication scheme %q", name)
+		}
+	}
+	return schemes, nil
+}
+
+func allows(schemes []AuthScheme, scheme AuthScheme) bool {
+	for _, s := range schemes {
+		if s == scheme {
+			return true
+		}
+	}
+	return false
+}
@@ -1,13 +1,19 @@
 package middleware
 
 import (
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
 	"net"
 	"net/http"
 	"production-go-api-template/config"
+	"production-go-api-template/pkg/audit"
+	"production-go-api-template/pkg/auth"
 	"production-go-api-template/pkg/constants"
+	"production-go-api-template/pkg/contextkeys"
 	"production-go-api-template/pkg/logger"
 	"production-go-api-template/pkg/router"
 	"strconv"
@@ -17,120 +23,305 @@ import (
 )
 
 const (
-	splitParts             = 2
-	secondPartIndex        = 1
-	resetFailuresTo        = 0
-	cleanupMultiplier      = 10
-	delayThreshold         = 0
-	baseDecimal            = 10
-	allowedTimeSkewSeconds = 300
+	resetFailuresTo    = 0
+	cleanupMultiplier  = 10
+	baseDecimal        = 10
+	maxSignedBodySize  = 10 << 20
+	maxNonceLength     = 128
+	nonceTTLMultiplier = 2
+	ipv4Bits           = 32
+	ipv6Bits           = 128
 )
 
-type ipState struct {
-	failures     int
-	blockedUntil time.Time
-	lastSeen     time.Time
-	slowdown     time.Duration
-}
-
 type Authenticator struct {
-	apiToken      string
-	hmacSecret    string
-	ipFailures    map[string]*ipState
-	ipBlocks      map[string]time.Time
-	mu            sync.Mutex
-	maxFailures   int
-	failWindow    time.Duration
-	blockDuration time.Duration
-	cleanupTick   time.Duration
-	slowdownStep  time.Duration
-	slowdownMax   time.Duration
-	log           *logger.Logger
+	clients        *auth.Registry
+	allowV1        bool
+	requireNonce   bool
+	maxSkew        time.Duration
+	nonces         NonceStore
+	store          SecurityStore
+	access         *IPAccessList
+	schemes        SchemeTable
+	verifiers      []TokenVerifier
+	clientTokens   TokenVerifier
+	auditor        Auditor
+	maxFailures    int
+	blockDuration  time.Duration
+	cleanupTick    time.Duration
+	slowdownMax    time.Duration
+	slowdownMode   string
+	delaySlots     chan struct{}
+	cleanupOnce    sync.Once
+	subnetBlocking bool
+	subnetPrefixV4 int
+	subnetPrefixV6 int
+	log            *logger.Logger
 }
 
-func NewAuthenticator(authCfg config.ConfAuth, secCfg config.ConfSecurity, log *logger.Logger) *Authenticator {
+func NewAuthenticator(authCfg config.ConfAuth, clients *auth.Registry, secCfg config.ConfSecurity, log *logger.Logger) *Authenticator {
 	a := &Authenticator{
-		apiToken:      authCfg.APITokens,
-		hmacSecret:    authCfg.HMACSecrets,
-		ipFailures:    make(map[string]*ipState),
-		ipBlocks:      make(map[string]time.Time),
-		maxFailures:   secCfg.MaxFailures,
-		failWindow:    secCfg.FailWindow,
-		blockDuration: secCfg.BlockDuration,
-		cleanupTick:   secCfg.CleanupTick,
-		slowdownStep:  secCfg.SlowdownStep,
-		slowdownMax:   secCfg.SlowdownMax,
-		log:           log,
-	}
-
-	go a.cleanupLoop()
+		clients:        clients,
+		allowV1:        authCfg.SignatureV1Enabled,
+		requireNonce:   authCfg.NonceRequired,
+		maxSkew:        authCfg.TimestampSkew,
+		nonces:         NewMemoryNonceStore(secCfg.NonceCacheSize),
+		store:          NewMemorySecurityStore(secCfg),
+		access:         &IPAccessList{},
+		schemes:        DefaultSchemeTable(),
+		auditor:        nopAuditor{},
+		maxFailures:    secCfg.MaxFailures,
+		blockDuration:  secCfg.BlockDuration,
+		cleanupTick:    secCfg.CleanupTick,
+		slowdownMax:    secCfg.SlowdownMax,
+		slowdownMode:   secCfg.SlowdownMode,
+		delaySlots:     make(chan struct{}, secCfg.SlowdownMaxConcurrent),
+		subnetBlocking: secCfg.SubnetBlocking,
+		subnetPrefixV4: secCfg.SubnetPrefixV4,
+		subnetPrefixV6: secCfg.SubnetPrefixV6,
+		log:            log,
+	}
+
+	return a
+}
+
+func (a *Authenticator) WithNonceStore(store NonceStore) *Authenticator {
+	a.nonces = store
+	return a
+}
+
+func (a *Authenticator) WithSecurityStore(store SecurityStore) *Authenticator {
+	a.store = store
+	return a
+}
+
+func (a *Authenticator) WithAccessList(list *IPAccessList) *Authenticator {
+	a.access = list
+	return a
+}
+
+func (a *Authenticator) WithSchemes(table SchemeTable, verifiers ...TokenVerifier) *Authenticator {
+	a.schemes = table
+	a.verifiers = verifiers
+	return a
+}
+
+// WithClientTokens accepts bearer tokens this server issued itself, such as
+// OAuth access tokens. Unlike external JWTs, their subject is the ID of a
+// registered client, so v must reject tokens of unknown or disabled clients.
+func (a *Authenticator) WithClientTokens(v TokenVerifier) *Authenticator {
+	a.clientTokens = v
+	return a
+}
+
+func (a *Authenticator) WithAuditor(auditor Auditor) *Authenticator {
+	a.auditor = auditor
 	return a
 }
 
 func (a *Authenticator) Middleware() Middleware {
+	a.cleanupOnce.Do(func() { go a.cleanupLoop() })
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			ip := clientIP(r)
 
-			if a.handleBlockedIP(r, w, ip) {
+			// Every rejection carries the server clock so clients can correct
+			// their timestamps; it is removed again once the request is let through.
+			w.Header().Set(auth.HeaderServerTime, strconv.FormatInt(time.Now().Unix(), baseDecimal))
+
+			if a.rejectIP(w, r, ip) {
 				return
 			}
 
-			a.handleSlowdown(ip)
-
-			token, ok := a.extractBearerToken(w, r, ip)
+			authenticated, ok := a.authenticate(w, r, ip)
 			if !ok {
 				return
 			}
+			w.Header().Del(auth.HeaderServerTime)
 
-			if token != a.apiToken {
-				a.recordFailure(ip)
-				router.RespondWithError(r, w, http.StatusForbidden, "invalid token", nil)
-				return
-			}
+			a.audit(authenticated, audit.EventAuthSuccess, contextkeys.GetClientID(authenticated.Context()), constants.EmptyString)
+			a.resetIP(r.Context(), ip)
 
-			if !a.handleSignature(w, r, ip, token) {
-				return
-			}
+			next.ServeHTTP(w, authenticated)
+		})
+	}
+}
+
+func (a *Authenticator) authenticate(w http.ResponseWriter, r *http.Request, ip string) (*http.Request, bool) {
+	schemes := a.schemes.ForPath(r.URL.Path)
+	cert := auth.VerifiedClientCertificate(r)
+
+	if cert != nil && allows(schemes, SchemeMTLS) && r.Header.Get(auth.HeaderAuthorization) == constants.EmptyString {
+		return a.authenticateCertificate(w, r, ip, cert)
+	}
+
+	token, ok := a.extractBearerToken(w, r, ip)
+	if !ok {
+		return r, false
+	}
+
+	var authenticated *http.Request
+	switch {
+	case a.acceptsTokens() && allows(schemes, SchemeJWT) && auth.LooksLikeJWT(token):
+		authenticated, ok = a.authenticateJWT(w, r, ip, token)
+	case allows(schemes, SchemeSignature):
+		authenticated, ok = a.authenticateSignature(w, r, ip, token)
+	default:
+		a.fail(r, ip, audit.EventBadToken, constants.EmptyString, "unsupported authentication scheme")
+		router.RespondWithError(r, w, http.StatusUnauthorized, "unsupported authentication scheme", nil)
+		return r, false
+	}
+	if !ok {
+		return r, false
+	}
+
+	if !a.checkCertificateBinding(w, authenticated, ip, cert) {
+		return r, false
+	}
+	return authenticated, true
+}
 
-			a.resetIP(ip)
+// Guard applies the IP deny list, blocking and slowdown without requiring
+// credentials. Public endpoints that check credentials themselves, such as
+// the OAuth token endpoint, report failures back through RecordFailure.
+func (a *Authenticator) Guard() Middleware {
+	a.cleanupOnce.Do(func() { go a.cleanupLoop() })
 
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if a.rejectIP(w, r, clientIP(r)) {
+				return
+			}
 			next.ServeHTTP(w, r)
 		})
 	}
 }
 
+func (a *Authenticator) RecordFailure(r *http.Request, clientID, detail string) {
+	ip := contextkeys.GetClientIP(r.Context())
+	if ip == constants.EmptyString {
+		return
+	}
+	a.fail(r, ip, audit.EventBadToken, clientID, detail)
+}
+
+func (a *Authenticator) rejectIP(w http.ResponseWriter, r *http.Request, ip string) bool {
+	if a.access.Denied(ip) {
+		a.audit(r, audit.EventIPDenied, constants.EmptyString, "deny list")
+		a.log.Warnf("Denied request from IP %s to %s %s", ip, r.Method, r.URL.Path)
+		router.RespondWithError(r, w, http.StatusForbidden, "access denied", nil)
+		return true
+	}
+
+	if a.access.Allowed(ip) {
+		return false
+	}
+
+	if a.handleBlockedIP(r, w, ip) {
+		return true
+	}
+
+	return a.handleSlowdown(w, r, ip)
+}
+
+func (a *Authenticator) authenticateSignature(w http.ResponseWriter, r *http.Request, ip, token string) (*http.Request, bool) {
+	client, ok := a.clients.Lookup(token)
+	if !ok {
+		a.fail(r, ip, audit.EventBadToken, constants.EmptyString, "invalid token")
+		router.RespondWithError(r, w, http.StatusForbidden, "invalid token", nil)
+		return r, false
+	}
+
+	if !client.Enabled {
+		a.fail(r, ip, audit.EventBadToken, client.ID, "client disabled")
+		a.log.Warnf("Request from disabled client %s from IP %s", client.ID, ip)
+		router.RespondWithError(r, w, http.StatusForbidden, "client disabled", nil)
+		return r, false
+	}
+
+	if !a.handleSignature(w, r, ip, token, client) {
+		return r, false
+	}
+
+	return withClientID(r, client.ID, client.GrantedScopes()), true
+}
+
 func (a *Authenticator) handleBlockedIP(r *http.Request, w http.ResponseWriter, ip string) bool {
-	if a.isBlocked(ip) {
-		a.log.Warnf("Blocked request from IP %s to %s %s", ip, r.Method, r.URL.Path)
-		router.RespondWithError(r, w, http.StatusForbidden, "blocked", nil)
+	kind, err := a.blockKind(r.Context(), ip)
+	if err != nil {
+		// Letting the request through would lift every block while the
+		// store is down, so the check fails closed like the nonce store.
+		router.RespondWithError(r, w, http.StatusServiceUnavailable, "block check failed, retry later", err)
 		return true
 	}
-	return false
+
+	switch kind {
+	case BlockKindIP:
+		a.audit(r, audit.EventIPBlocked, constants.EmptyString, "request rejected")
+	case BlockKindSubnet:
+		a.audit(r, audit.EventSubnetBlocked, constants.EmptyString, "request rejected")
+	default:
+		return false
+	}
+
+	a.log.Warnf("Blocked request from IP %s to %s %s", ip, r.Method, r.URL.Path)
+	router.RespondWithError(r, w, http.StatusForbidden, "blocked", nil)
+	return true
 }
 
-func (a *Authenticator) handleSlowdown(ip string) {
-	if delay := a.getSlowdown(ip); delay > time.Duration(delayThreshold) {
-		time.Sleep(delay)
+func (a *Authenticator) handleSlowdown(w http.ResponseWriter, r *http.Request, ip string) bool {
+	delay := a.getSlowdown(r.Context(), ip)
+	if delay <= 0 {
+		return false
+	}
+
+	if a.slowdownMode == config.SlowdownModeReject {
+		a.respondTooManyRequests(w, r, delay)
+		return true
+	}
+
+	select {
+	case a.delaySlots <- struct{}{}:
+		defer func() { <-a.delaySlots }()
+	default:
+		a.log.Warnf("Slowdown capacity exhausted, rejecting IP %s", ip)
+		a.respondTooManyRequests(w, r, delay)
+		return true
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return false
+	case <-r.Context().Done():
+		return true
 	}
 }
 
+func (a *Authenticator) respondTooManyRequests(w http.ResponseWriter, r *http.Request, delay time.Duration) {
+	retryAfter := int64(math.Ceil(delay.Seconds()))
+	w.Header().Set(headerRetryAfter, strconv.FormatInt(retryAfter, baseDecimal))
+	router.RespondWithError(r, w, http.StatusTooManyRequests, "too many failed attempts", nil)
+}
+
 func (a *Authenticator) extractBearerToken(w http.ResponseWriter, r *http.Request, ip string) (string, bool) {
-	auth := r.Header.Get("Authorization")
-	if !strings.HasPrefix(auth, "Bearer ") {
-		a.recordFailure(ip)
+	header := r.Header.Get(auth.HeaderAuthorization)
+	if !strings.HasPrefix(header, auth.BearerPrefix) {
+		a.fail(r, ip, audit.EventBadToken, constants.EmptyString, "missing bearer")
 		router.RespondWithError(r, w, http.StatusUnauthorized, "missing bearer", nil)
 		return "", false
 	}
-	return strings.TrimPrefix(auth, "Bearer "), true
+	return strings.TrimPrefix(header, auth.BearerPrefix), true
 }
 
-func (a *Authenticator) handleSignature(w http.ResponseWriter, r *http.Request, ip, token string) bool {
-	signature := r.Header.Get("X-Signature")
-	timestampStr := r.Header.Get("X-Timestamp")
+func (a *Authenticator) handleSignature(w http.ResponseWriter, r *http.Request, ip, token string, client auth.Client) bool {
+	signature := r.Header.Get(auth.HeaderSignature)
+	timestampStr := r.Header.Get(auth.HeaderTimestamp)
 
 	if signature == constants.EmptyString || timestampStr == constants.EmptyString {
-		a.recordFailure(ip)
+		a.fail(r, ip, audit.EventBadSignature, client.ID, "missing signature or timestamp")
 		a.log.Warnf("Missing signature or timestamp from IP %s", ip)
 		router.RespondWithError(r, w, http.StatusUnauthorized, "missing signature or timestamp", nil)
 		return false
@@ -138,144 +329,269 @@ func (a *Authenticator) handleSignature(w http.ResponseWriter, r *http.Request,
 
 	ts, err := strconv.ParseInt(timestampStr, baseDecimal, constants.BigInt)
 	if err != nil {
-		a.recordFailure(ip)
+		a.fail(r, ip, audit.EventTimestampSkew, client.ID, "invalid timestamp")
 		a.log.Warnf("Invalid timestamp format from IP %s", ip)
 		router.RespondWithError(r, w, http.StatusUnauthorized, "invalid timestamp", nil)
 		return false
 	}
-	now := time.Now().Unix()
-	if ts > now+allowedTimeSkewSeconds || ts < now-allowedTimeSkewSeconds {
-		a.recordFailure(ip)
+	if skew := time.Since(time.Unix(ts, 0)).Abs(); skew > a.maxSkew {
+		a.fail(r, ip, audit.EventTimestampSkew, client.ID, "timestamp out of range")
 		a.log.Warnf("Timestamp out of range from IP %s", ip)
 		router.RespondWithError(r, w, http.StatusUnauthorized, "timestamp out of range", nil)
 		return false
 	}
 
-	message := token + constants.PipeSeparator + timestampStr + constants.PipeSeparator + r.Method + constants.PipeSeparator + r.URL.Path
-	if !validateHMAC(message, signature, a.hmacSecret) {
-		a.recordFailure(ip)
+	keyID := r.Header.Get(auth.HeaderKeyID)
+	secrets, err := client.SigningSecrets(keyID, time.Now())
+	if err != nil {
+		a.fail(r, ip, audit.EventBadSignature, client.ID, err.Error())
+		a.log.Warnf("Rejected key %q for client %s from IP %s: %v", keyID, client.ID, ip, err)
+		status := http.StatusUnauthorized
+		if errors.Is(err, auth.ErrUnknownKey) {
+			status = http.StatusForbidden
+		}
+		router.RespondWithError(r, w, status, err.Error(), nil)
+		return false
+	}
+
+	canonical, ok := a.canonicalRequest(w, r, ip, client.ID, token, timestampStr)
+	if !ok {
+		return false
+	}
+
+	message, err := canonical.Message()
+	if err != nil {
+		a.fail(r, ip, audit.EventBadSignature, client.ID, err.Error())
+		router.RespondWithError(r, w, http.StatusUnauthorized, err.Error(), nil)
+		return false
+	}
+
+	if !auth.VerifyAny(message, signature, secrets) {
+		a.fail(r, ip, audit.EventBadSignature, client.ID, "invalid signature")
 		a.log.Warnf("Invalid HMAC signature from IP %s", ip)
 		router.RespondWithError(r, w, http.StatusForbidden, "invalid signature", nil)
 		return false
 	}
 
-	return true
+	return a.handleNonce(w, r, ip, client.ID, canonical.Nonce)
 }
 
-func validateHMAC(message, signature, secret string) bool {
-	mac := hmac.New(sha256.New, []byte(secret))
-	mac.Write([]byte(message))
-	expectedMAC := mac.Sum(nil)
-	sigBytes, err := hex.DecodeString(signature)
+func (a *Authenticator) handleNonce(w http.ResponseWriter, r *http.Request, ip, clientID, nonce string) bool {
+	if nonce == constants.EmptyString {
+		return true
+	}
+
+	expiresAt := time.Now().Add(nonceTTLMultiplier * a.maxSkew)
+	fresh, err := a.nonces.Remember(clientID+constants.PipeSeparator+nonce, expiresAt)
+	if errors.Is(err, ErrNonceStoreFull) {
+		a.log.Errorf("Nonce store full, rejecting request from client %s", clientID)
+		router.RespondWithError(r, w, http.StatusServiceUnavailable, "nonce store is full, retry later", err)
+		return false
+	}
 	if err != nil {
+		router.RespondWithError(r, w, http.StatusInternalServerError, "nonce check failed", err)
 		return false
 	}
-	return hmac.Equal(expectedMAC, sigBytes)
+	if !fresh {
+		a.fail(r, ip, audit.EventBadSignature, clientID, "nonce already used")
+		a.log.Warnf("Replayed nonce for client %s from IP %s", clientID, ip)
+		router.RespondWithError(r, w, http.StatusUnauthorized, "nonce already used", nil)
+		return false
+	}
+	return true
+}
+
+func withClientID(r *http.Request, clientID string, scopes []string) *http.Request {
+	annotateLogEntry(r, clientID)
+	ctx := context.WithValue(r.Context(), contextkeys.CtxKeyClientID, clientID)
+	ctx = context.WithValue(ctx, contextkeys.CtxKeyScopes, scopes)
+	return r.WithContext(ctx)
 }
 
-func clientIP(r *http.Request) string {
-	if h := r.Header.Get("X-Forwarded-For"); h != "" {
-		parts := strings.Split(h, ",")
-		return strings.TrimSpace(parts[constants.ZeroIndex])
+func (a *Authenticator) canonicalRequest(w http.ResponseWriter, r *http.Request, ip, clientID, token, timestamp string) (auth.CanonicalRequest, bool) {
+	canonical := auth.CanonicalRequest{
+		Version:   r.Header.Get(auth.HeaderSignatureVersion),
+		Token:     token,
+		Timestamp: timestamp,
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		Nonce:     r.Header.Get(auth.HeaderNonce),
 	}
-	host, _, err := net.SplitHostPort(r.RemoteAddr)
-	if err != nil {
-		return r.RemoteAddr
+	if canonical.Version == constants.EmptyString {
+		canonical.Version = auth.SignatureV1
 	}
-	return host
-}
 
-func (a *Authenticator) isBlocked(ipStr string) bool {
-	a.mu.Lock()
-	defer a.mu.Unlock()
+	if canonical.Nonce == constants.EmptyString && a.requireNonce {
+		a.fail(r, ip, audit.EventBadSignature, clientID, "missing nonce")
+		router.RespondWithError(r, w, http.StatusUnauthorized, "missing nonce", nil)
+		return canonical, false
+	}
+	if len(canonical.Nonce) > maxNonceLength {
+		a.fail(r, ip, audit.EventBadSignature, clientID, "invalid nonce")
+		router.RespondWithError(r, w, http.StatusUnauthorized, "invalid nonce", nil)
+		return canonical, false
+	}
 
-	now := time.Now()
+	switch canonical.Version {
+	case auth.SignatureV1:
+		if !a.allowV1 {
+			a.fail(r, ip, audit.EventBadSignature, clientID, "signature version v1 is disabled")
+			a.log.Warnf("Rejected v1 signature from IP %s", ip)
+			router.RespondWithError(r, w, http.StatusUnauthorized, "signature version v1 is disabled", nil)
+			return canonical, false
+		}
+		return canonical, true
+	case auth.SignatureV2:
+	default:
+		a.fail(r, ip, audit.EventBadSignature, clientID, auth.ErrUnsupportedVersion.Error())
+		router.RespondWithError(r, w, http.StatusUnauthorized, auth.ErrUnsupportedVersion.Error(), nil)
+		return canonical, false
+	}
 
-	if state, ok := a.ipFailures[ipStr]; ok && now.Before(state.blockedUntil) {
-		return true
+	claimedDigest := strings.ToLower(r.Header.Get(auth.HeaderContentSHA256))
+	if claimedDigest == constants.EmptyString {
+		a.fail(r, ip, audit.EventBadSignature, clientID, "missing content digest")
+		router.RespondWithError(r, w, http.StatusUnauthorized, "missing content digest", nil)
+		return canonical, false
 	}
 
-	ip := net.ParseIP(ipStr)
-	if ip == nil {
-		return false
+	body, err := readSignedBody(r)
+	if err != nil {
+		a.fail(r, ip, audit.EventBadSignature, clientID, "failed to read request body")
+		router.RespondWithError(r, w, http.StatusBadRequest, "failed to read request body", err)
+		return canonical, false
 	}
 
-	ip4 := ip.To4()
-	if ip4 != nil {
-		subnet := ip4.Mask(net.CIDRMask(24, 32)).String()
-		blockUntil, blocked := a.ipBlocks[subnet]
-		return blocked && now.Before(blockUntil)
+	canonical.BodySHA256 = auth.BodySHA256(body)
+	if canonical.BodySHA256 != claimedDigest {
+		a.fail(r, ip, audit.EventBadSignature, clientID, "content digest mismatch")
+		a.log.Warnf("Content digest mismatch from IP %s", ip)
+		router.RespondWithError(r, w, http.StatusForbidden, "content digest mismatch", nil)
+		return canonical, false
 	}
 
-	return false
+	canonical.Query = r.URL.Query()
+	return canonical, true
 }
 
-func (a *Authenticator) recordFailure(ipStr string) {
-	a.mu.Lock()
-	defer a.mu.Unlock()
+func readSignedBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil {
+		return nil, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxSignedBodySize+1))
+	_ = r.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxSignedBodySize {
+		return nil, errors.New("request body too large")
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
 
+// blockKind reports whether the IP is blocked on its own or through its
+// subnet, and returns an empty string when it is not blocked.
+func (a *Authenticator) blockKind(ctx context.Context, ipStr string) (string, error) {
 	now := time.Now()
-	state, exists := a.ipFailures[ipStr]
-	if !exists {
-		state = &ipState{}
-		a.ipFailures[ipStr] = state
+	blocked, err := a.store.IsBlocked(ctx, ipStr, constants.EmptyString, now)
+	if err != nil {
+		a.log.Errorf("Failed to check block state for IP %s: %v", ipStr, err)
+		return constants.EmptyString, err
+	}
+	if blocked {
+		return BlockKindIP, nil
 	}
 
-	if now.Sub(state.lastSeen) > a.failWindow {
-		state.failures = resetFailuresTo
+	subnet, ok := a.subnetKey(ipStr)
+	if !ok {
+		return constants.EmptyString, nil
+	}
+	blocked, err = a.store.IsBlocked(ctx, ipStr, subnet, now)
+	if err != nil {
+		a.log.Errorf("Failed to check block state for subnet %s: %v", subnet, err)
+		return constants.EmptyString, err
+	}
+	if blocked {
+		return BlockKindSubnet, nil
 	}
-	state.failures++
-	state.lastSeen = now
+	return constants.EmptyString, nil
+}
 
-	a.log.Infof("Recording failure for IP %s (total failures: %d/%d)", ipStr, state.failures, a.maxFailures)
+func (a *Authenticator) fail(r *http.Request, ip string, event audit.EventType, clientID, detail string) {
+	a.audit(r, event, clientID, detail)
 
-	if state.slowdown < a.slowdownMax {
-		state.slowdown += a.slowdownStep
-		if state.slowdown == a.slowdownMax {
-			a.log.Infof("IP %s reached maximum slowdown of %s", ipStr, a.slowdownMax)
-		}
+	record, subnet := a.recordFailure(r.Context(), ip)
+	if !record.Blocked {
+		return
+	}
+	a.audit(r, audit.EventIPBlocked, clientID, fmt.Sprintf("blocked for %s after %d failures", a.blockDuration, record.Failures))
+	if subnet != constants.EmptyString {
+		a.audit(r, audit.EventSubnetBlocked, clientID, fmt.Sprintf("subnet %s blocked for %s", subnet, a.blockDuration))
+	}
+}
+
+func (a *Authenticator) recordFailure(ctx context.Context, ipStr string) (FailureRecord, string) {
+	if a.access.Allowed(ipStr) {
+		return FailureRecord{}, constants.EmptyString
 	}
 
-	if state.failures >= a.maxFailures {
-		state.blockedUntil = now.Add(a.blockDuration)
-		a.log.Warnf("Blocking IP %s for %s after %d failures", ipStr, a.blockDuration, state.failures)
-		a.blockSubnet(ipStr)
+	subnet, _ := a.subnetKey(ipStr)
+	record, err := a.store.RecordFailure(ctx, ipStr, subnet, time.Now())
+	if err != nil {
+		a.log.Errorf("Failed to record failure for IP %s: %v", ipStr, err)
+		return FailureRecord{}, constants.EmptyString
 	}
+
+	a.log.Infof("Recording failure for IP %s (total failures: %d/%d)", ipStr, record.Failures, a.maxFailures)
+
+	if record.ReachedSlowdown {
+		a.log.Infof("IP %s reached maximum slowdown of %s", ipStr, a.slowdownMax)
+	}
+
+	if record.Blocked {
+		a.log.Warnf("Blocking IP %s for %s after %d failures", ipStr, a.blockDuration, record.Failures)
+		if subnet != constants.EmptyString {
+			a.log.Warnf("Blocking subnet %s for %s", subnet, a.blockDuration)
+		}
+	}
+	return record, subnet
 }
 
-func (a *Authenticator) blockSubnet(ipStr string) {
-	ip := net.ParseIP(ipStr)
-	if ip == nil {
-		return
+func (a *Authenticator) subnetKey(ipStr string) (string, bool) {
+	if !a.subnetBlocking {
+		return constants.EmptyString, false
 	}
-	if ip.IsLoopback() {
-		return
+	ip := net.ParseIP(ipStr)
+	if ip == nil || ip.IsLoopback() {
+		return constants.EmptyString, false
 	}
-	ip4 := ip.To4()
-	if ip4 == nil {
-		return
+	if ip4 := ip.To4(); ip4 != nil {
+		mask := net.CIDRMask(a.subnetPrefixV4, ipv4Bits)
+		return (&net.IPNet{IP: ip4.Mask(mask), Mask: mask}).String(), true
 	}
-	subnet := ip4.Mask(net.CIDRMask(24, 32)).String()
-	a.ipBlocks[subnet] = time.Now().Add(a.blockDuration)
-	a.log.Warnf("Blocking subnet %s/24 for %s", subnet, a.blockDuration)
+	mask := net.CIDRMask(a.subnetPrefixV6, ipv6Bits)
+	return (&net.IPNet{IP: ip.Mask(mask), Mask: mask}).String(), true
 }
 
-func (a *Authenticator) resetIP(ipStr string) {
-	a.mu.Lock()
-	defer a.mu.Unlock()
-	if _, exists := a.ipFailures[ipStr]; exists {
+func (a *Authenticator) resetIP(ctx context.Context, ipStr string) {
+	reset, err := a.store.Reset(ctx, ipStr)
+	if err != nil {
+		a.log.Errorf("Failed to reset failure count for IP %s: %v", ipStr, err)
+		return
+	}
+	if reset {
 		a.log.Infof("Resetting failure count for IP %s after successful authentication", ipStr)
-		delete(a.ipFailures, ipStr)
 	}
 }
 
-func (a *Authenticator) getSlowdown(ipStr string) time.Duration {
-	a.mu.Lock()
-	defer a.mu.Unlock()
-	if state, ok := a.ipFailures[ipStr]; ok {
-		return state.slowdown
+func (a *Authenticator) getSlowdown(ctx context.Context, ipStr string) time.Duration {
+	delay, err := a.store.Slowdown(ctx, ipStr)
+	if err != nil {
+		a.log.Errorf("Failed to read slowdown for IP %s: %v", ipStr, err)
+		return time.Duration(resetFailuresTo)
 	}
-	return time.Duration(resetFailuresTo)
+	return delay
 }
 
 func (a *Authenticator) cleanupLoop() {
@@ -283,30 +599,13 @@ func (a *Authenticator) cleanupLoop() {
 	defer ticker.Stop()
 
 	for range ticker.C {
-		a.mu.Lock()
-		now := time.Now()
-
-		ipCountBefore := len(a.ipFailures)
-		subnetCountBefore := len(a.ipBlocks)
-
-		for ip, state := range a.ipFailures {
-			if now.Sub(state.lastSeen) > a.failWindow*time.Duration(cleanupMultiplier) {
-				delete(a.ipFailures, ip)
-			}
-		}
-		for subnet, until := range a.ipBlocks {
-			if until.Before(now) {
-				delete(a.ipBlocks, subnet)
-			}
+		stats, err := a.store.Cleanup(context.Background(), time.Now())
+		if err != nil {
+			a.log.Errorf("Security cleanup failed: %v", err)
+			continue
 		}
 
-		ipCountAfter := len(a.ipFailures)
-		subnetCountAfter := len(a.ipBlocks)
-
-		a.mu.Unlock()
-
 		a.log.Infof("Security stats: %d active IP blocks, %d active subnet blocks. Cleaned %d IPs, %d subnets.",
-			ipCountAfter, subnetCountAfter,
-			ipCountBefore-ipCountAfter, subnetCountBefore-subnetCountAfter)
+			stats.ActiveIPs, stats.ActiveSubnets, stats.RemovedIPs, stats.RemovedSubnets)
 	}
 }
@@ -0,0 +1,191 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"production-go-api-template/config"
+	"testing"
+	"time"
+)
+
+// brokenSecurityStore fails every block lookup, as a SQLite store does when
+// its database is unavailable.
+type brokenSecurityStore struct {
+	SecurityStore
+}
+
+func (brokenSecurityStore) IsBlocked(context.Context, string, string, time.Time) (bool, error) {
+	return false, errors.New("database is locked")
+}
+
+func TestBlockCheckFailsClosed(t *testing.T) {
+	a := newTestAuthenticator(t, testSecurityConfig())
+	a.WithSecurityStore(brokenSecurityStore{SecurityStore: a.store})
+
+	if got := serve(a.Middleware()(okHandler), signedRequest("198.51.100.1:4000", "")).Code; got != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", got, http.StatusServiceUnavailable)
+	}
+}
+
+func TestMiddlewareOrdering(t *testing.T) {
+	const ip = "198.51.100.1"
+
+	tests := []struct {
+		name      string
+		allow     []string
+		deny      []string
+		blocked   bool
+		signed    bool
+		want      int
+		wantCount bool
+	}{
+		{"signed request passes", nil, nil, false, true, http.StatusNoContent, false},
+		{"missing token counts as a failure", nil, nil, false, false, http.StatusUnauthorized, true},
+		{"deny before token", nil, []string{ip}, false, true, http.StatusForbidden, false},
+		{"deny without token is not a failure", nil, []string{ip}, false, false, http.StatusForbidden, false},
+		{"deny before allow", []string{ip}, []string{ip}, false, true, http.StatusForbidden, false},
+		{"block before token", nil, nil, true, true, http.StatusForbidden, false},
+		{"allow before block", []string{ip}, nil, true, true, http.StatusNoContent, false},
+		{"allow still needs a token", []string{ip}, nil, true, false, http.StatusUnauthorized, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAuthenticator(t, testSecurityConfig())
+			a.WithAccessList(mustAccessList(t, tt.allow, tt.deny))
+			if tt.blocked {
+				block(t, a, BlockKindIP, ip)
+			}
+
+			r := signedRequest(ip+":4000", "")
+			if !tt.signed {
+				r.Header.Del("Authorization")
+			}
+			if got := serve(a.Middleware()(okHandler), r).Code; got != tt.want {
+				t.Fatalf("status = %d, want %d", got, tt.want)
+			}
+
+			delay, err := a.store.Slowdown(context.Background(), ip)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if counted := delay > 0; counted != tt.wantCount {
+				t.Errorf("failure recorded = %v, want %v", counted, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestNonceHandling(t *testing.T) {
+	tests := []struct {
+		name      string
+		cacheSize int
+		nonces    []string
+		want      []int
+	}{
+		{"fresh nonces", 10, []string{"n1", "n2"}, []int{http.StatusNoContent, http.StatusNoContent}},
+		{"replayed nonce", 10, []string{"n1", "n1"}, []int{http.StatusNoContent, http.StatusUnauthorized}},
+		{"full nonce store", 1, []string{"n1", "n2"}, []int{http.StatusNoContent, http.StatusServiceUnavailable}},
+		{"replay into a full store", 1, []string{"n1", "n1"}, []int{http.StatusNoContent, http.StatusUnauthorized}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secCfg := testSecurityConfig()
+			secCfg.NonceCacheSize = tt.cacheSize
+			h := newTestAuthenticator(t, secCfg).Middleware()(okHandler)
+
+			for i, nonce := range tt.nonces {
+				if got := serve(h, signedRequest("198.51.100.1:4000", nonce)).Code; got != tt.want[i] {
+					t.Fatalf("request %d with nonce %q: status = %d, want %d", i, nonce, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// failOnce records one authentication failure for ip, which puts it into
+// slowdown for one SlowdownStep.
+func failOnce(t *testing.T, h http.Handler, ip string) {
+	t.Helper()
+	r := signedRequest(ip+":4000", "")
+	r.Header.Del("Authorization")
+	if got := serve(h, r).Code; got != http.StatusUnauthorized {
+		t.Fatalf("failed request: status = %d, want %d", got, http.StatusUnauthorized)
+	}
+}
+
+func TestSlowdown(t *testing.T) {
+	const ip = "198.51.100.1"
+
+	tests := []struct {
+		name           string
+		mode           string
+		step           time.Duration
+		slotsTaken     bool
+		want           int
+		wantRetryAfter string
+	}{
+		{"reject mode answers 429", config.SlowdownModeReject, 2500 * time.Millisecond, false, http.StatusTooManyRequests, "3"},
+		{"delay mode waits and lets the request through", config.SlowdownModeDelay, 10 * time.Millisecond, false, http.StatusNoContent, ""},
+		{"delay mode without free slots answers 429", config.SlowdownModeDelay, time.Second, true, http.StatusTooManyRequests, "1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secCfg := testSecurityConfig()
+			secCfg.SlowdownMode = tt.mode
+			secCfg.SlowdownStep = tt.step
+			a := newTestAuthenticator(t, secCfg)
+			h := a.Middleware()(okHandler)
+			failOnce(t, h, ip)
+
+			if tt.slotsTaken {
+				a.delaySlots <- struct{}{}
+				defer func() { <-a.delaySlots }()
+			}
+			w := serve(h, signedRequest(ip+":4000", ""))
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d", w.Code, tt.want)
+			}
+			if got := w.Header().Get("Retry-After"); got != tt.wantRetryAfter {
+				t.Errorf("Retry-After = %q, want %q", got, tt.wantRetryAfter)
+			}
+		})
+	}
+}
+
+func TestSlowdownStopsWhenTheClientGoesAway(t *testing.T) {
+	const ip = "198.51.100.1"
+
+	secCfg := testSecurityConfig()
+	secCfg.SlowdownMode = config.SlowdownModeDelay
+	secCfg.SlowdownStep = time.Hour
+	secCfg.SlowdownMax = time.Hour
+	a := newTestAuthenticator(t, secCfg)
+
+	called := false
+	h := a.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called = true }))
+	failOnce(t, h, ip)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		serve(h, signedRequest(ip+":4000", "").WithContext(ctx))
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("slowdown kept waiting after the request context was cancelled")
+	}
+	if called {
+		t.Error("the request reached the handler after its context was cancelled")
+	}
+	if len(a.delaySlots) != 0 {
+		t.Errorf("%d delay slots still taken", len(a.delaySlots))
+	}
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"production-go-api-template/pkg/constants"
+	"production-go-api-template/pkg/contextkeys"
+	"production-go-api-template/pkg/router"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidBlockTarget  = errors.New("target must be an IP address or a subnet")
+	ErrSubnetPrefix        = errors.New("subnet prefix does not match the configured subnet blocking prefix")
+	ErrSubnetBlockDisabled = errors.New("subnet blocking is disabled")
+)
+
+func (a *Authenticator) RequireAdmin() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			client, ok := a.clients.Get(contextkeys.GetClientID(r.Context()))
+			if !ok || !client.Enabled || !client.Admin {
+				a.log.Warnf("Non-admin client %q denied access to %s %s", client.ID, r.Method, r.URL.Path)
+				router.RespondWithError(r, w, http.StatusForbidden, "admin access required", nil)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (a *Authenticator) ListBlocks(ctx context.Context) ([]BlockEntry, error) {
+	entries, err := a.store.ListBlocks(ctx, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range entries {
+		if entries[i].Kind != BlockKindSubnet {
+			continue
+		}
+		_, subnet, err := net.ParseCIDR(entries[i].Target)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if e.Kind == BlockKindIP && subnet.Contains(net.ParseIP(e.Target)) {
+				entries[i].Failures += e.Failures
+			}
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].BlockedUntil.Before(entries[j].BlockedUntil)
+	})
+	return entries, nil
+}
+
+func (a *Authenticator) Block(ctx context.Context, target, reason string, duration time.Duration) (BlockEntry, error) {
+	kind, normalized, err := a.parseBlockTarget(target)
+	if err != nil {
+		return BlockEntry{}, err
+	}
+	if duration <= 0 {
+		duration = a.blockDuration
+	}
+
+	entry := BlockEntry{
+		Target:       normalized,
+		Kind:         kind,
+		BlockedUntil: time.Now().Add(duration),
+		Reason:       reason,
+	}
+	if err := a.store.Block(ctx, kind, normalized, entry.BlockedUntil, reason); err != nil {
+		return BlockEntry{}, err
+	}
+
+	a.log.Warnf("Client %s manually blocked %s %s for %s: %s",
+		contextkeys.GetClientID(ctx), kind, normalized, duration, reason)
+	return entry, nil
+}
+
+func (a *Authenticator) Unblock(ctx context.Context, target string) (bool, error) {
+	kind, normalized, err := a.parseBlockTarget(target)
+	if err != nil {
+		return false, err
+	}
+
+	removed, err := a.store.Unblock(ctx, kind, normalized)
+	if err != nil {
+		return false, err
+	}
+	if removed {
+		a.log.Warnf("Client %s unblocked %s %s", contextkeys.GetClientID(ctx), kind, normalized)
+	}
+	return removed, nil
+}
+
+func (a *Authenticator) parseBlockTarget(target string) (string, string, error) {
+	target = strings.TrimSpace(target)
+	if !strings.Contains(target, "/") {
+		ip := net.ParseIP(target)
+		if ip == nil {
+			return constants.EmptyString, constants.EmptyString, ErrInvalidBlockTarget
+		}
+		return BlockKindIP, ip.String(), nil
+	}
+
+	ip, subnet, err := net.ParseCIDR(target)
+	if err != nil {
+		return constants.EmptyString, constants.EmptyString, ErrInvalidBlockTarget
+	}
+	if !a.subnetBlocking {
+		return constants.EmptyString, constants.EmptyString, ErrSubnetBlockDisabled
+	}
+
+	key, ok := a.subnetKey(ip.String())
+	if !ok || key != subnet.String() {
+		return constants.EmptyString, constants.EmptyString, ErrSubnetPrefix
+	}
+	return BlockKindSubnet, key, nil
+}
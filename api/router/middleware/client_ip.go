@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"production-go-api-template/pkg/clientip"
+	"production-go-api-template/pkg/constants"
+	"production-go-api-template/pkg/contextkeys"
+)
+
+func ResolveClientIP(res *clientip.Resolver) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), contextkeys.CtxKeyClientIP, res.ClientIP(r))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func clientIP(r *http.Request) string {
+	if ip := contextkeys.GetClientIP(r.Context()); ip != constants.EmptyString {
+		return ip
+	}
+	return clientip.PeerIP(r)
+}
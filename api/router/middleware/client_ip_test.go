@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"production-go-api-template/config"
+	"production-go-api-template/pkg/clientip"
+	"testing"
+	"time"
+)
+
+const (
+	proxyAddr = "10.0.0.1:4000"
+	realIP    = "198.51.100.1"
+	spoofedIP = "203.0.113.9"
+)
+
+// spoofed sends r through the trusted proxy, which appends the real client
+// address to X-Forwarded-For, after the client tried to claim spoofedIP in
+// every forwarding header.
+func spoofed(r *http.Request) *http.Request {
+	r.RemoteAddr = proxyAddr
+	r.Header.Add("X-Forwarded-For", spoofedIP)
+	r.Header.Add("X-Forwarded-For", realIP)
+	r.Header.Set("Forwarded", "for="+spoofedIP)
+	r.Header.Set("X-Real-IP", spoofedIP)
+	return r
+}
+
+func newTestResolver(t *testing.T) *clientip.Resolver {
+	t.Helper()
+	res, err := clientip.NewResolver([]string{"10.0.0.0/8"}, config.ClientIPHeaderXForwardedFor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestSpoofedHeadersDoNotChangeAccessDecisions(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(t *testing.T, a *Authenticator)
+		want  int
+	}{
+		{"real IP denied", func(t *testing.T, a *Authenticator) {
+			a.WithAccessList(mustAccessList(t, nil, []string{realIP}))
+		}, http.StatusForbidden},
+		{"spoofed IP denied", func(t *testing.T, a *Authenticator) {
+			a.WithAccessList(mustAccessList(t, nil, []string{spoofedIP}))
+		}, http.StatusNoContent},
+		{"real IP blocked", func(t *testing.T, a *Authenticator) {
+			block(t, a, BlockKindIP, realIP)
+		}, http.StatusForbidden},
+		{"real subnet blocked", func(t *testing.T, a *Authenticator) {
+			block(t, a, BlockKindSubnet, "198.51.100.0/24")
+		}, http.StatusForbidden},
+		{"spoofed IP blocked", func(t *testing.T, a *Authenticator) {
+			block(t, a, BlockKindIP, spoofedIP)
+		}, http.StatusNoContent},
+		{"spoofed IP allowed past a real block", func(t *testing.T, a *Authenticator) {
+			a.WithAccessList(mustAccessList(t, []string{spoofedIP}, nil))
+			block(t, a, BlockKindIP, realIP)
+		}, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAuthenticator(t, testSecurityConfig())
+			tt.setup(t, a)
+			h := CreateStack(ResolveClientIP(newTestResolver(t)), a.Middleware())(okHandler)
+
+			if got := serve(h, spoofed(signedRequest(proxyAddr, ""))).Code; got != tt.want {
+				t.Errorf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpoofedHeadersDoNotChangeFailureTracking(t *testing.T) {
+	a := newTestAuthenticator(t, testSecurityConfig())
+	h := CreateStack(ResolveClientIP(newTestResolver(t)), a.Middleware())(okHandler)
+
+	if got := serve(h, spoofed(httptest.NewRequest(http.MethodGet, "/api/v1/items", nil))).Code; got != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", got, http.StatusUnauthorized)
+	}
+
+	for ip, want := range map[string]bool{realIP: true, spoofedIP: false, "10.0.0.1": false} {
+		delay, err := a.store.Slowdown(context.Background(), ip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := delay > 0; got != want {
+			t.Errorf("failure recorded against %s = %v, want %v", ip, got, want)
+		}
+	}
+}
+
+func TestSpoofedHeadersDoNotChangeRateLimitKey(t *testing.T) {
+	rl, err := NewRateLimiter(config.ConfRateLimit{Default: "1/1h", CacheSize: 10}, testClients(t), testLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := CreateStack(ResolveClientIP(newTestResolver(t)), rl.Middleware())(okHandler)
+
+	if got := serve(h, spoofed(httptest.NewRequest(http.MethodGet, "/", nil))).Code; got != http.StatusNoContent {
+		t.Fatalf("first request status = %d", got)
+	}
+
+	// A different spoofed address must not buy a fresh bucket.
+	r := spoofed(httptest.NewRequest(http.MethodGet, "/", nil))
+	r.Header.Set("Forwarded", "for=192.0.2.1")
+	r.Header.Set("X-Real-IP", "192.0.2.1")
+	r.Header["X-Forwarded-For"] = []string{"192.0.2.1", realIP}
+	if got := serve(h, r).Code; got != http.StatusTooManyRequests {
+		t.Errorf("second request from %s status = %d, want %d", realIP, got, http.StatusTooManyRequests)
+	}
+
+	// Another client behind the same proxy has its own bucket.
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = proxyAddr
+	r.Header.Set("X-Forwarded-For", "198.51.100.2")
+	if got := serve(h, r).Code; got != http.StatusNoContent {
+		t.Errorf("request from another client status = %d, want %d", got, http.StatusNoContent)
+	}
+}
+
+func mustAccessList(t *testing.T, allow, deny []string) *IPAccessList {
+	t.Helper()
+	list, err := NewIPAccessList(allow, deny)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return list
+}
+
+func block(t *testing.T, a *Authenticator, kind, target string) {
+	t.Helper()
+	if err := a.store.Block(context.Background(), kind, target, time.Now().Add(time.Hour), "test"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -2,8 +2,35 @@ package middleware
 
 import (
 	"net/http"
+	"production-go-api-template/pkg/auth"
+	"strings"
 )
 
+const corsListSeparator = ", "
+
+// corsAllowedHeaders lists the request headers a browser may send, including
+// every header that signed requests carry.
+var corsAllowedHeaders = strings.Join([]string{
+	"Content-Type",
+	auth.HeaderAuthorization,
+	"If-Match",
+	auth.HeaderSignature,
+	auth.HeaderTimestamp,
+	auth.HeaderKeyID,
+	auth.HeaderSignatureVersion,
+	auth.HeaderContentSHA256,
+	auth.HeaderNonce,
+}, corsListSeparator)
+
+// corsExposedHeaders lists the response headers scripts may read.
+var corsExposedHeaders = strings.Join([]string{
+	"ETag",
+	auth.HeaderServerTime,
+	HeaderRateLimitLimit,
+	HeaderRateLimitRemaining,
+	headerRetryAfter,
+}, corsListSeparator)
+
 func CORS(allowedOrigins []string) Middleware {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -15,8 +42,9 @@ func CORS(allowedOrigins []string) Middleware {
 				}
 			}
 
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS, DELETE, PUT")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS, DELETE, PUT, PATCH")
+			w.Header().Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+			w.Header().Set("Access-Control-Expose-Headers", corsExposedHeaders)
 			if r.Method == "OPTIONS" {
 				w.WriteHeader(http.StatusNoContent)
 				return
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCORSPreflight(t *testing.T) {
+	r := httptest.NewRequest(http.MethodOptions, "/api/v1/items", nil)
+	r.Header.Set("Origin", "https://app.example")
+	w := serve(CORS([]string{"https://app.example"})(okHandler), r)
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusNoContent)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example" {
+		t.Errorf("Access-Control-Allow-Origin = %q", got)
+	}
+
+	allowed := strings.Split(w.Header().Get("Access-Control-Allow-Headers"), corsListSeparator)
+	for _, h := range []string{"Content-Type", "Authorization", "If-Match", "X-Signature", "X-Timestamp", "X-Key-Id", "X-Signature-Version", "X-Content-SHA256", "X-Nonce"} {
+		if !containsHeader(allowed, h) {
+			t.Errorf("Access-Control-Allow-Headers %v does not allow %s", allowed, h)
+		}
+	}
+
+	exposed := strings.Split(w.Header().Get("Access-Control-Expose-Headers"), corsListSeparator)
+	for _, h := range []string{"ETag", "X-Server-Time", "RateLimit-Limit", "RateLimit-Remaining", "Retry-After"} {
+		if !containsHeader(exposed, h) {
+			t.Errorf("Access-Control-Expose-Headers %v does not expose %s", exposed, h)
+		}
+	}
+}
+
+func TestCORSUnknownOrigin(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/items", nil)
+	r.Header.Set("Origin", "https://evil.example")
+	w := serve(CORS([]string{"https://app.example"})(okHandler), r)
+
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want none", got)
+	}
+	if w.Code != http.StatusNoContent {
+		t.Errorf("status = %d, want the request passed on", w.Code)
+	}
+}
+
+func containsHeader(list []string, header string) bool {
+	for _, h := range list {
+		if strings.EqualFold(h, header) {
+			return true
+		}
+	}
+	return false
+}
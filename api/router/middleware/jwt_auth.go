@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"production-go-api-template/pkg/audit"
+	"production-go-api-template/pkg/auth"
+	"production-go-api-template/pkg/constants"
+	"production-go-api-template/pkg/contextkeys"
+	"production-go-api-template/pkg/router"
+	"time"
+)
+
+// ExternalSubjectPrefix namespaces the subject of tokens from external
+// issuers, so a token with sub "admin" can never act as the registered client
+// "admin".
+const ExternalSubjectPrefix = "jwt:"
+
+type TokenVerifier interface {
+	Verify(token string, now time.Time) (auth.Claims, error)
+}
+
+func (a *Authenticator) acceptsTokens() bool {
+	return len(a.verifiers) > 0 || a.clientTokens != nil
+}
+
+// verifyToken tries the external verifiers, then the verifier of tokens this
+// server issued to its clients. A verifier that does not know the signing key
+// defers to the next one; any other error is final. The subject of external
+// tokens is returned with ExternalSubjectPrefix.
+func (a *Authenticator) verifyToken(token string, now time.Time) (auth.Claims, error) {
+	for _, v := range a.verifiers {
+		claims, err := v.Verify(token, now)
+		if errors.Is(err, auth.ErrUnknownSigningKey) {
+			continue
+		}
+		if err == nil {
+			claims.Subject = ExternalSubjectPrefix + claims.Subject
+		}
+		return claims, err
+	}
+	if a.clientTokens != nil {
+		return a.clientTokens.Verify(token, now)
+	}
+	return auth.Claims{}, auth.ErrUnknownSigningKey
+}
+
+func (a *Authenticator) authenticateJWT(w http.ResponseWriter, r *http.Request, ip, token string) (*http.Request, bool) {
+	claims, err := a.verifyToken(token, time.Now())
+	if err != nil {
+		a.fail(r, ip, audit.EventBadToken, constants.EmptyString, err.Error())
+		a.log.Warnf("Rejected JWT from IP %s: %v", ip, err)
+		router.RespondWithError(r, w, http.StatusUnauthorized, err.Error(), nil)
+		return r, false
+	}
+
+	r = withClientID(r, claims.Subject, claims.Scopes)
+	ctx := context.WithValue(r.Context(), contextkeys.CtxKeyClaims, claims)
+	return r.WithContext(ctx), true
+}
@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"production-go-api-template/config"
+	"production-go-api-template/pkg/auth"
+	"production-go-api-template/pkg/logger"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	testToken  = "tok"
+	testSecret = "secret"
+)
+
+var testLog = logger.New(zerolog.Disabled)
+
+func testSecurityConfig() config.ConfSecurity {
+	return config.ConfSecurity{
+		MaxFailures:           3,
+		FailWindow:            time.Minute,
+		BlockDuration:         time.Hour,
+		CleanupTick:           time.Hour,
+		SlowdownStep:          time.Second,
+		SlowdownMax:           5 * time.Second,
+		SlowdownMode:          config.SlowdownModeReject,
+		SlowdownMaxConcurrent: 1,
+		NonceCacheSize:        100,
+		SubnetBlocking:        true,
+		SubnetPrefixV4:        24,
+		SubnetPrefixV6:        64,
+	}
+}
+
+func testClients(t *testing.T) *auth.Registry {
+	t.Helper()
+	clients, err := auth.NewRegistry([]auth.Client{{ID: "alice", Token: testToken, Secret: testSecret, Enabled: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return clients
+}
+
+func newTestAuthenticator(t *testing.T, secCfg config.ConfSecurity) *Authenticator {
+	t.Helper()
+	authCfg := config.ConfAuth{SignatureV1Enabled: true, TimestampSkew: 5 * time.Minute}
+	return NewAuthenticator(authCfg, testClients(t), secCfg, testLog)
+}
+
+// signedRequest returns a request carrying a valid v1 signature of the test
+// client, sent from remoteAddr.
+func signedRequest(remoteAddr, nonce string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/items", nil)
+	r.RemoteAddr = remoteAddr
+	ts := strconv.FormatInt(time.Now().Unix(), baseDecimal)
+	canonical := auth.CanonicalRequest{Token: testToken, Timestamp: ts, Method: r.Method, Path: r.URL.Path, Nonce: nonce}
+	message, _ := canonical.Message()
+	if nonce != "" {
+		r.Header.Set(auth.HeaderNonce, nonce)
+	}
+	r.Header.Set(auth.HeaderAuthorization, auth.BearerPrefix+testToken)
+	r.Header.Set(auth.HeaderTimestamp, ts)
+	r.Header.Set(auth.HeaderSignature, auth.Sign(message, testSecret))
+	return r
+}
+
+func serve(h http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+})
+
+func TestCreateStackOrder(t *testing.T) {
+	var order []string
+	mark := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	serve(CreateStack(mark("first"), mark("second"), mark("third"))(okHandler), httptest.NewRequest(http.MethodGet, "/", nil))
+	if got := strings.Join(order, ","); got != "first,second,third" {
+		t.Errorf("middleware order = %s, want first,second,third", got)
+	}
+}
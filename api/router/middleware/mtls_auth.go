@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"crypto/x509"
+	"net/http"
+	"production-go-api-template/pkg/audit"
+	"production-go-api-template/pkg/auth"
+	"production-go-api-template/pkg/constants"
+	"production-go-api-template/pkg/contextkeys"
+	"production-go-api-template/pkg/router"
+)
+
+func (a *Authenticator) authenticateCertificate(w http.ResponseWriter, r *http.Request, ip string, cert *x509.Certificate) (*http.Request, bool) {
+	client, ok := a.clients.LookupCertificate(cert)
+	if !ok {
+		a.fail(r, ip, audit.EventBadCertificate, constants.EmptyString, "unknown client certificate")
+		a.log.Warnf("Unknown client certificate %q from IP %s", cert.Subject.String(), ip)
+		router.RespondWithError(r, w, http.StatusForbidden, "unknown client certificate", nil)
+		return r, false
+	}
+
+	if !client.Enabled {
+		a.fail(r, ip, audit.EventBadCertificate, client.ID, "client disabled")
+		a.log.Warnf("Request from disabled client %s from IP %s", client.ID, ip)
+		router.RespondWithError(r, w, http.StatusForbidden, "client disabled", nil)
+		return r, false
+	}
+
+	return withClientID(r, client.ID, client.GrantedScopes()), true
+}
+
+// checkCertificateBinding runs after token authentication. A verified client
+// certificate must belong to the same client as the token, and clients with
+// require_certificate cannot authenticate without their certificate.
+func (a *Authenticator) checkCertificateBinding(w http.ResponseWriter, r *http.Request, ip string, cert *x509.Certificate) bool {
+	clientID := contextkeys.GetClientID(r.Context())
+
+	var certClient auth.Client
+	if cert != nil {
+		certClient, _ = a.clients.LookupCertificate(cert)
+		if certClient.ID != constants.EmptyString && certClient.ID != clientID {
+			a.fail(r, ip, audit.EventBadCertificate, clientID, "client certificate does not match credentials")
+			a.log.Warnf("Client certificate of %s presented with credentials of %s from IP %s", certClient.ID, clientID, ip)
+			router.RespondWithError(r, w, http.StatusForbidden, "client certificate does not match credentials", nil)
+			return false
+		}
+	}
+
+	client, ok := a.clients.Get(clientID)
+	if ok && client.RequireCertificate && certClient.ID != clientID {
+		a.fail(r, ip, audit.EventBadCertificate, clientID, "client certificate required")
+		a.log.Warnf("Client %s authenticated without its certificate from IP %s", clientID, ip)
+		router.RespondWithError(r, w, http.StatusForbidden, "client certificate required", nil)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrNonceStoreFull is returned when every stored nonce is still live.
+// Dropping one of them would let its request be replayed, so the store
+// refuses new nonces until some expire.
+var ErrNonceStoreFull = errors.New("nonce store is full")
+
+type NonceStore interface {
+	// Remember stores the nonce until expiresAt and reports whether it was
+	// unused. A false result means the nonce has already been seen.
+	Remember(nonce string, expiresAt time.Time) (bool, error)
+}
+
+type nonceEntry struct {
+	nonce     string
+	expiresAt time.Time
+}
+
+type memoryNonceStore struct {
+	mu      sync.Mutex
+	maxSize int
+	seen    map[string]time.Time
+	queue   []nonceEntry
+}
+
+func NewMemoryNonceStore(maxSize int) NonceStore {
+	return &memoryNonceStore{
+		maxSize: maxSize,
+		seen:    make(map[string]time.Time),
+	}
+}
+
+func (s *memoryNonceStore) Remember(nonce string, expiresAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.evictExpired(now)
+
+	if until, ok := s.seen[nonce]; ok && now.Before(until) {
+		return false, nil
+	}
+
+	if len(s.queue) >= s.maxSize {
+		return false, ErrNonceStoreFull
+	}
+
+	s.seen[nonce] = expiresAt
+	s.queue = append(s.queue, nonceEntry{nonce: nonce, expiresAt: expiresAt})
+	return true, nil
+}
+
+// evictExpired relies on the queue being ordered by expiry, which holds
+// because every nonce is kept for the same TTL.
+func (s *memoryNonceStore) evictExpired(now time.Time) {
+	for len(s.queue) > 0 && !now.Before(s.queue[0].expiresAt) {
+		s.evictOldest()
+	}
+}
+
+func (s *memoryNonceStore) evictOldest() {
+	oldest := s.queue[0]
+	s.queue = s.queue[1:]
+	if until, ok := s.seen[oldest.nonce]; ok && until.Equal(oldest.expiresAt) {
+		delete(s.seen, oldest.nonce)
+	}
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryNonceStore(t *testing.T) {
+	type step struct {
+		nonce   string
+		ttl     time.Duration
+		want    bool
+		wantErr error
+	}
+
+	tests := []struct {
+		name    string
+		maxSize int
+		steps   []step
+	}{
+		{"fresh nonces", 10, []step{
+			{"a", time.Minute, true, nil},
+			{"b", time.Minute, true, nil},
+		}},
+		{"replay is rejected", 10, []step{
+			{"a", time.Minute, true, nil},
+			{"a", time.Minute, false, nil},
+		}},
+		{"expired nonce can be reused", 10, []step{
+			{"a", 0, true, nil},
+			{"a", time.Minute, true, nil},
+			{"a", time.Minute, false, nil},
+		}},
+		{"full store refuses new nonces", 2, []step{
+			{"a", time.Minute, true, nil},
+			{"b", time.Minute, true, nil},
+			{"c", time.Minute, false, ErrNonceStoreFull},
+		}},
+		{"full store still detects replays", 1, []step{
+			{"a", time.Minute, true, nil},
+			{"a", time.Minute, false, nil},
+		}},
+		{"expired entries make room", 2, []step{
+			{"a", 0, true, nil},
+			{"b", 0, true, nil},
+			{"c", time.Minute, true, nil},
+			{"d", time.Minute, true, nil},
+			{"e", time.Minute, false, ErrNonceStoreFull},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryNonceStore(tt.maxSize)
+			for i, s := range tt.steps {
+				got, err := store.Remember(s.nonce, time.Now().Add(s.ttl))
+				if !errors.Is(err, s.wantErr) {
+					t.Fatalf("step %d: Remember(%q) error = %v, want %v", i, s.nonce, err, s.wantErr)
+				}
+				if got != s.want {
+					t.Fatalf("step %d: Remember(%q) = %v, want %v", i, s.nonce, got, s.want)
+				}
+			}
+		})
+	}
+}
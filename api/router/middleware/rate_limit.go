@@ -0,0 +1,212 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"production-go-api-template/config"
+	"production-go-api-template/pkg/auth"
+	"production-go-api-template/pkg/constants"
+	"production-go-api-template/pkg/contextkeys"
+	"production-go-api-template/pkg/logger"
+	"production-go-api-template/pkg/router"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	HeaderRateLimitLimit     = "RateLimit-Limit"
+	HeaderRateLimitRemaining = "RateLimit-Remaining"
+	headerRetryAfter         = "Retry-After"
+
+	rateLimitSeparator = "/"
+	rateLimitKeyClient = "client:"
+	rateLimitKeyIP     = "ip:"
+)
+
+// RateLimit allows Requests per Window, refilled continuously, with bursts
+// of up to Requests.
+type RateLimit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// ParseRateLimit reads limits such as "600/1m".
+func ParseRateLimit(s string) (RateLimit, error) {
+	count, window, ok := strings.Cut(strings.TrimSpace(s), rateLimitSeparator)
+	if !ok {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q, expected requests/window", s)
+	}
+	requests, err := strconv.Atoi(count)
+	if err != nil || requests <= constants.ZeroIndex {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q: requests must be a positive integer", s)
+	}
+	d, err := time.ParseDuration(window)
+	if err != nil || d <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q: window must be a positive duration", s)
+	}
+	return RateLimit{Requests: requests, Window: d}, nil
+}
+
+func (l RateLimit) perSecond() float64 {
+	return float64(l.Requests) / l.Window.Seconds()
+}
+
+type rateLimitRoute struct {
+	prefix string
+	limit  RateLimit
+}
+
+// RateLimiter throttles requests per authenticated client, or per IP for
+// anonymous requests. Each route group listed in RATE_LIMIT_ROUTES has its own
+// buckets; all other paths share the default group.
+type RateLimiter struct {
+	defaults  RateLimit
+	routes    []rateLimitRoute
+	overrides map[string]map[string]RateLimit
+	store     RateLimitStore
+	log       *logger.Logger
+}
+
+// NewRateLimiter parses the configured limits and the rate_limits of every
+// client. Client entries look like "6000/1m" for all route groups or
+// "/api/v1/items=12000/1m" for a group from RATE_LIMIT_ROUTES.
+func NewRateLimiter(cfg config.ConfRateLimit, clients *auth.Registry, log *logger.Logger) (*RateLimiter, error) {
+	defaults, err := ParseRateLimit(cfg.Default)
+	if err != nil {
+		return nil, err
+	}
+
+	rl := &RateLimiter{
+		defaults:  defaults,
+		overrides: make(map[string]map[string]RateLimit),
+		store:     NewMemoryRateLimitStore(cfg.CacheSize),
+		log:       log,
+	}
+
+	for _, entry := range cfg.Routes {
+		entry = strings.TrimSpace(entry)
+		if entry == constants.EmptyString {
+			continue
+		}
+		prefix, limit, err := parseRouteRateLimit(entry)
+		if err != nil {
+			return nil, err
+		}
+		if prefix == constants.EmptyString {
+			return nil, fmt.Errorf("invalid route rate limit %q", entry)
+		}
+		rl.routes = append(rl.routes, rateLimitRoute{prefix: prefix, limit: limit})
+	}
+	sort.Slice(rl.routes, func(i, j int) bool {
+		return len(rl.routes[i].prefix) > len(rl.routes[j].prefix)
+	})
+
+	for _, client := range clients.Clients() {
+		for _, entry := range client.RateLimits {
+			prefix, limit, err := parseRouteRateLimit(entry)
+			if err != nil {
+				return nil, fmt.Errorf("client %q: %w", client.ID, err)
+			}
+			if prefix != constants.EmptyString && !rl.hasGroup(prefix) {
+				return nil, fmt.Errorf("client %q: %q is not a route group in RATE_LIMIT_ROUTES", client.ID, prefix)
+			}
+			if rl.overrides[client.ID] == nil {
+				rl.overrides[client.ID] = make(map[string]RateLimit)
+			}
+			rl.overrides[client.ID][prefix] = limit
+		}
+	}
+
+	return rl, nil
+}
+
+func (rl *RateLimiter) WithStore(store RateLimitStore) *RateLimiter {
+	rl.store = store
+	return rl
+}
+
+// Middleware must run after authentication so the client ID is known;
+// requests without one are counted against their IP.
+func (rl *RateLimiter) Middleware() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			clientID := contextkeys.GetClientID(r.Context())
+			group, limit := rl.limitFor(clientID, r.URL.Path)
+
+			key := rateLimitKeyIP + contextkeys.GetClientIP(r.Context())
+			if clientID != constants.EmptyString {
+				key = rateLimitKeyClient + clientID
+			}
+
+			result, err := rl.store.Take(r.Context(), group+constants.PipeSeparator+key, limit, time.Now())
+			if err != nil {
+				rl.log.Errorf("Rate limit store failed, allowing request: %v", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set(HeaderRateLimitLimit, strconv.Itoa(limit.Requests))
+			w.Header().Set(HeaderRateLimitRemaining, strconv.Itoa(result.Remaining))
+
+			if !result.Allowed {
+				retryAfter := int64(math.Ceil(result.RetryAfter.Seconds()))
+				rl.log.Warnf("Rate limit exceeded for %s on %s %s", key, r.Method, r.URL.Path)
+				w.Header().Set(headerRetryAfter, strconv.FormatInt(retryAfter, baseDecimal))
+				router.RespondWithError(r, w, http.StatusTooManyRequests, "rate limit exceeded", nil)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// limitFor returns the route group of path and the limit that applies to the
+// client there: its own limit for the group, then its own default, then the
+// configured limit of the group.
+func (rl *RateLimiter) limitFor(clientID, path string) (string, RateLimit) {
+	group, limit := constants.EmptyString, rl.defaults
+	for _, route := range rl.routes {
+		if path == route.prefix || strings.HasPrefix(path, route.prefix+"/") {
+			group, limit = route.prefix, route.limit
+			break
+		}
+	}
+
+	if overrides, ok := rl.overrides[clientID]; ok {
+		if l, ok := overrides[group]; ok {
+			return group, l
+		}
+		if l, ok := overrides[constants.EmptyString]; ok {
+			return group, l
+		}
+	}
+	return group, limit
+}
+
+func (rl *RateLimiter) hasGroup(prefix string) bool {
+	for _, route := range rl.routes {
+		if route.prefix == prefix {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRouteRateLimit reads "prefix=limit" or a bare limit, which yields an
+// empty prefix.
+func parseRouteRateLimit(entry string) (string, RateLimit, error) {
+	prefix, value, ok := strings.Cut(strings.TrimSpace(entry), routeSchemeAssign)
+	if !ok {
+		limit, err := ParseRateLimit(prefix)
+		return constants.EmptyString, limit, err
+	}
+	if !strings.HasPrefix(prefix, "/") {
+		return constants.EmptyString, RateLimit{}, fmt.Errorf("invalid route rate limit %q", entry)
+	}
+	limit, err := ParseRateLimit(value)
+	return strings.TrimRight(prefix, "/"), limit, err
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"container/list"
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+type RateLimitResult struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+// RateLimitStore keeps one token bucket per key. Take removes a token from
+// the bucket, creating a full one on first use, and reports what is left.
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, limit RateLimit, now time.Time) (RateLimitResult, error)
+}
+
+type tokenBucket struct {
+	key     string
+	tokens  float64
+	updated time.Time
+	limit   RateLimit
+}
+
+// refill adds the tokens earned since the last update, capped at the burst.
+func (b *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.updated); elapsed > 0 {
+		b.tokens = math.Min(float64(b.limit.Requests), b.tokens+elapsed.Seconds()*b.limit.perSecond())
+		b.updated = now
+	}
+}
+
+// memoryRateLimitStore keeps at most maxSize buckets. They are ordered by
+// last use, so a full store drops the least recently used one in O(1).
+type memoryRateLimitStore struct {
+	mu      sync.Mutex
+	maxSize int
+	buckets map[string]*list.Element
+	lru     *list.List
+}
+
+func NewMemoryRateLimitStore(maxSize int) RateLimitStore {
+	return &memoryRateLimitStore{
+		maxSize: maxSize,
+		buckets: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+func (s *memoryRateLimitStore) Take(_ context.Context, key string, limit RateLimit, now time.Time) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var b *tokenBucket
+	if elem, ok := s.buckets[key]; ok {
+		s.lru.MoveToFront(elem)
+		b = elem.Value.(*tokenBucket)
+	} else {
+		if len(s.buckets) >= s.maxSize {
+			s.evictOldest()
+		}
+		b = &tokenBucket{key: key, tokens: float64(limit.Requests), updated: now, limit: limit}
+		s.buckets[key] = s.lru.PushFront(b)
+	}
+
+	b.limit = limit
+	b.refill(now)
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / limit.perSecond() * float64(time.Second))
+		return RateLimitResult{Remaining: 0, RetryAfter: wait}, nil
+	}
+
+	b.tokens--
+	return RateLimitResult{Allowed: true, Remaining: int(b.tokens)}, nil
+}
+
+func (s *memoryRateLimitStore) evictOldest() {
+	oldest := s.lru.Back()
+	if oldest == nil {
+		return
+	}
+	s.lru.Remove(oldest)
+	delete(s.buckets, oldest.Value.(*tokenBucket).key)
+}
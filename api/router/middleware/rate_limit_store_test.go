@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryRateLimitStoreRefill(t *testing.T) {
+	limit := RateLimit{Requests: 2, Window: 2 * time.Second}
+	start := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name           string
+		after          time.Duration
+		wantAllowed    bool
+		wantRemaining  int
+		wantRetryAfter time.Duration
+	}{
+		{"first request uses the burst", 0, true, 1, 0},
+		{"second request empties the bucket", 0, true, 0, 0},
+		{"empty bucket rejects", 0, false, 0, time.Second},
+		{"partial refill is not enough", 500 * time.Millisecond, false, 0, 500 * time.Millisecond},
+		{"one token after a second", time.Second, true, 0, 0},
+		{"refill is capped at the burst", time.Hour, true, 1, 0},
+	}
+
+	store := NewMemoryRateLimitStore(10)
+	now := start
+	for _, tt := range tests {
+		now = now.Add(tt.after)
+		got, err := store.Take(context.Background(), "k", limit, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Allowed != tt.wantAllowed || got.Remaining != tt.wantRemaining || got.RetryAfter != tt.wantRetryAfter {
+			t.Errorf("%s: Take() = %+v, want allowed %v, remaining %d, retry after %s",
+				tt.name, got, tt.wantAllowed, tt.wantRemaining, tt.wantRetryAfter)
+		}
+	}
+}
+
+func TestMemoryRateLimitStoreEvictsLeastRecentlyUsed(t *testing.T) {
+	limit := RateLimit{Requests: 1, Window: time.Hour}
+	now := time.Unix(1700000000, 0)
+	store := NewMemoryRateLimitStore(2)
+	take := func(key string) bool {
+		t.Helper()
+		result, err := store.Take(context.Background(), key, limit, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result.Allowed
+	}
+
+	take("active")
+	take("idle")
+	// Using the active bucket again makes idle the least recently used.
+	if take("active") {
+		t.Fatal("active bucket was not empty")
+	}
+	for _, key := range []string{"flood-1", "flood-2", "flood-3"} {
+		take(key)
+		take("active")
+	}
+
+	if take("active") {
+		t.Error("active bucket was evicted and refilled by the flood")
+	}
+	if !take("idle") {
+		t.Error("idle bucket was kept while the store was full")
+	}
+}
@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"production-go-api-template/config"
+	"production-go-api-template/pkg/auth"
+	"production-go-api-template/pkg/contextkeys"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    RateLimit
+		wantErr bool
+	}{
+		{"600/1m", RateLimit{Requests: 600, Window: time.Minute}, false},
+		{" 10/1s ", RateLimit{Requests: 10, Window: time.Second}, false},
+		{"600", RateLimit{}, true},
+		{"0/1m", RateLimit{}, true},
+		{"-1/1m", RateLimit{}, true},
+		{"x/1m", RateLimit{}, true},
+		{"10/0s", RateLimit{}, true},
+		{"10/minute", RateLimit{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseRateLimit(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseRateLimit(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseRateLimit(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRateLimiterLimitFor(t *testing.T) {
+	clients, err := auth.NewRegistry([]auth.Client{
+		{ID: "batch", Token: "t1", Secret: "s1", Enabled: true, RateLimits: []string{"100/1m", "/api/v1/items=1000/1m"}},
+		{ID: "plain", Token: "t2", Secret: "s2", Enabled: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rl, err := NewRateLimiter(config.ConfRateLimit{
+		Default:   "10/1m",
+		Routes:    []string{"/api/v1/items=20/1m", "/api/v1/items/search=5/1m", "/oauth=3/1m"},
+		CacheSize: 10,
+	}, clients, testLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		clientID  string
+		path      string
+		wantGroup string
+		want      int
+	}{
+		{"default group", "", "/health", "", 10},
+		{"route group", "", "/api/v1/items/42", "/api/v1/items", 20},
+		{"longest prefix wins", "", "/api/v1/items/search", "/api/v1/items/search", 5},
+		{"prefix needs a path boundary", "", "/api/v1/itemsx", "", 10},
+		{"client without overrides", "plain", "/api/v1/items", "/api/v1/items", 20},
+		{"client group override", "batch", "/api/v1/items", "/api/v1/items", 1000},
+		{"client default override", "batch", "/oauth/token", "/oauth", 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			group, limit := rl.limitFor(tt.clientID, tt.path)
+			if group != tt.wantGroup || limit.Requests != tt.want {
+				t.Errorf("limitFor(%q, %q) = %q, %d, want %q, %d", tt.clientID, tt.path, group, limit.Requests, tt.wantGroup, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRateLimiterRejectsUnknownGroup(t *testing.T) {
+	clients, err := auth.NewRegistry([]auth.Client{
+		{ID: "batch", Token: "t1", Secret: "s1", Enabled: true, RateLimits: []string{"/api/v2=1000/1m"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewRateLimiter(config.ConfRateLimit{Default: "10/1m", CacheSize: 10}, clients, testLog); err == nil {
+		t.Fatal("NewRateLimiter() expected an error for a group missing from RATE_LIMIT_ROUTES")
+	}
+}
+
+func TestRateLimiterMiddleware(t *testing.T) {
+	rl, err := NewRateLimiter(config.ConfRateLimit{Default: "2/1h", CacheSize: 10}, testClients(t), testLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := rl.Middleware()(okHandler)
+
+	request := func(ip, clientID string) *http.Request {
+		ctx := context.WithValue(context.Background(), contextkeys.CtxKeyClientIP, ip)
+		if clientID != "" {
+			ctx = context.WithValue(ctx, contextkeys.CtxKeyClientID, clientID)
+		}
+		return httptest.NewRequest(http.MethodGet, "/api/v1/items", nil).WithContext(ctx)
+	}
+
+	tests := []struct {
+		name           string
+		ip             string
+		clientID       string
+		want           int
+		wantRemaining  string
+		wantRetryAfter string
+	}{
+		{"first anonymous request", "198.51.100.1", "", http.StatusNoContent, "1", ""},
+		{"second anonymous request", "198.51.100.1", "", http.StatusNoContent, "0", ""},
+		{"limit reached", "198.51.100.1", "", http.StatusTooManyRequests, "0", "1800"},
+		{"other IP has its own bucket", "198.51.100.2", "", http.StatusNoContent, "1", ""},
+		{"client is counted apart from its IP", "198.51.100.1", "alice", http.StatusNoContent, "1", ""},
+		{"client keeps its bucket across IPs", "198.51.100.3", "alice", http.StatusNoContent, "0", ""},
+		{"client limit reached", "198.51.100.4", "alice", http.StatusTooManyRequests, "0", "1800"},
+	}
+
+	for _, tt := range tests {
+		w := serve(h, request(tt.ip, tt.clientID))
+		if w.Code != tt.want {
+			t.Fatalf("%s: status = %d, want %d", tt.name, w.Code, tt.want)
+		}
+		if got := w.Header().Get(HeaderRateLimitLimit); got != "2" {
+			t.Errorf("%s: %s = %q, want 2", tt.name, HeaderRateLimitLimit, got)
+		}
+		if got := w.Header().Get(HeaderRateLimitRemaining); got != tt.wantRemaining {
+			t.Errorf("%s: %s = %q, want %q", tt.name, HeaderRateLimitRemaining, got, tt.wantRemaining)
+		}
+		if got := w.Header().Get(headerRetryAfter); got != tt.wantRetryAfter {
+			t.Errorf("%s: Retry-After = %q, want %q", tt.name, got, tt.wantRetryAfter)
+		}
+	}
+}
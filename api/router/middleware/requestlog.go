@@ -2,22 +2,28 @@ package middleware
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"io"
 	"net"
 	"net/http"
+	"net/url"
 	"production-go-api-template/pkg/constants"
 	"production-go-api-template/pkg/contextkeys"
 	"production-go-api-template/pkg/logger"
-	"strings"
 	"time"
 )
 
 const (
 	maxLogBodySize = 1024 * 10
+	redactedValue  = "[REDACTED]"
 )
 
+var sensitiveBodyFields = []string{"client_secret", "access_token", "token"}
+
 type logEntry struct {
 	RequestID      string              `json:"request_id,omitempty"`
+	ClientID       string              `json:"client_id,omitempty"`
 	ReceivedTime   time.Time           `json:"received_time"`
 	RequestMethod  string              `json:"request_method"`
 	RequestURL     string              `json:"request_url"`
@@ -48,13 +54,15 @@ func RequestLog(l *logger.Logger) func(http.Handler) http.Handler {
 
 			bodyBytes := readAndRestoreBody(r, l)
 			reqHeader := sanitizeHeaders(r.Header)
-			loggedReqBody := truncateBody(bodyBytes)
+			loggedReqBody := truncateBody(redactBody(bodyBytes))
 
 			le := newLogEntry(r, reqHeader, loggedReqBody, start)
 			le.ServerIP = getServerIP(r)
 
+			ctx := context.WithValue(r.Context(), contextkeys.CtxKeyRequestLog, le)
+
 			w2 := &responseStats{w: w}
-			next.ServeHTTP(w2, r)
+			next.ServeHTTP(w2, r.WithContext(ctx))
 
 			finalizeEntry(le, w2)
 			l.Info().Fields(entryFields(le)).Msg("http_request")
@@ -80,12 +88,50 @@ func sanitizeHeaders(header http.Header) map[string][]string {
 	}
 	for _, h := range []string{"Authorization", "X-Signature", "X-Timestamp"} {
 		if _, ok := reqHeader[h]; ok {
-			reqHeader[h] = []string{"[REDACTED]"}
+			reqHeader[h] = []string{redactedValue}
 		}
 	}
 	return reqHeader
 }
 
+// redactBody masks credentials in JSON objects and form bodies, such as the
+// OAuth token request and response.
+func redactBody(body []byte) []byte {
+	var obj map[string]any
+	if err := json.Unmarshal(body, &obj); err == nil {
+		redacted := false
+		for _, field := range sensitiveBodyFields {
+			if _, ok := obj[field]; ok {
+				obj[field] = redactedValue
+				redacted = true
+			}
+		}
+		if !redacted {
+			return body
+		}
+		if out, err := json.Marshal(obj); err == nil {
+			return out
+		}
+		return body
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return body
+	}
+	redacted := false
+	for _, field := range sensitiveBodyFields {
+		if form.Has(field) {
+			form.Set(field, redactedValue)
+			redacted = true
+		}
+	}
+	if !redacted {
+		return body
+	}
+	return []byte(form.Encode())
+}
+
 func truncateBody(body []byte) string {
 	if len(body) <= maxLogBodySize {
 		return string(body)
@@ -104,7 +150,7 @@ func newLogEntry(r *http.Request, header map[string][]string, body string, start
 		UserAgent:     r.UserAgent(),
 		Referer:       r.Referer(),
 		Proto:         r.Proto,
-		RemoteIP:      extractClientIP(r),
+		RemoteIP:      clientIP(r),
 	}
 }
 
@@ -117,6 +163,12 @@ func getRequestIDFromContext(r *http.Request) string {
 	return ""
 }
 
+func annotateLogEntry(r *http.Request, clientID string) {
+	if le, ok := r.Context().Value(contextkeys.CtxKeyRequestLog).(*logEntry); ok {
+		le.ClientID = clientID
+	}
+}
+
 func getServerIP(r *http.Request) string {
 	if addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok {
 		host, _, err := net.SplitHostPort(addr.String())
@@ -139,13 +191,14 @@ func finalizeEntry(le *logEntry, stats *responseStats) {
 	le.ResponseHeader = repHeader
 
 	fullResp := stats.bodyBuf.String()
-	le.ResponseBody = truncateBody([]byte(fullResp))
+	le.ResponseBody = truncateBody(redactBody([]byte(fullResp)))
 	le.Latency = time.Since(le.ReceivedTime)
 }
 
 func entryFields(le *logEntry) map[string]any {
 	return map[string]any{
 		"request_id":      le.RequestID,
+		"client_id":       le.ClientID,
 		"received_time":   le.ReceivedTime,
 		"method":          le.RequestMethod,
 		"url":             le.RequestURL,
@@ -163,18 +216,6 @@ func entryFields(le *logEntry) map[string]any {
 	}
 }
 
-func extractClientIP(r *http.Request) string {
-	if h := r.Header.Get("X-Forwarded-For"); h != "" {
-		parts := strings.Split(h, ",")
-		return strings.TrimSpace(parts[constants.ZeroIndex])
-	}
-	host, _, err := net.SplitHostPort(r.RemoteAddr)
-	if err != nil {
-		return r.RemoteAddr
-	}
-	return host
-}
-
 func (r *responseStats) Header() http.Header {
 	return r.w.Header()
 }
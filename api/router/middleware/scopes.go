@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"production-go-api-template/pkg/auth"
+	"production-go-api-template/pkg/contextkeys"
+	"production-go-api-template/pkg/logger"
+	"production-go-api-template/pkg/router"
+	"production-go-api-template/pkg/validator"
+	"strings"
+)
+
+// RequireScopes rejects authenticated requests whose credentials do not carry
+// every listed scope. It must run behind Authenticator.Middleware.
+func RequireScopes(scopes ...string) Middleware {
+	required := strings.Join(scopes, " ")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			missing := auth.MissingScopes(auth.ScopesFromContext(r.Context()), scopes)
+			if len(missing) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			clientID := contextkeys.GetClientID(r.Context())
+			log, err := validator.ExtractAndValidateContext[*logger.Logger](r.Context(), contextkeys.CtxKeyLogger)
+			if err == nil {
+				log.WithRequestID(r.Context()).Warnf("Client %q denied %s %s: missing scope %s",
+					clientID, r.Method, r.URL.Path, strings.Join(missing, " "))
+			}
+
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope=%q`, required))
+			router.RespondWithError(r, w, http.StatusForbidden,
+				fmt.Sprintf("insufficient scope: missing %s", strings.Join(missing, ", ")), nil)
+		})
+	}
+}
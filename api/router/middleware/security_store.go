@@ -0,0 +1,264 @@
+package middleware
+
+import (
+	"context"
+	"production-go-api-template/config"
+	"production-go-api-template/pkg/constants"
+	"sync"
+	"time"
+)
+
+const (
+	BlockKindIP     = "ip"
+	BlockKindSubnet = "subnet"
+
+	autoBlockReason = "too many authentication failures"
+)
+
+type ipState struct {
+	failures     int
+	blockedUntil time.Time
+	lastSeen     time.Time
+	slowdown     time.Duration
+	reason       string
+}
+
+type subnetBlock struct {
+	until  time.Time
+	reason string
+}
+
+type BlockEntry struct {
+	Target       string    `json:"target"`
+	Kind         string    `json:"kind"`
+	Failures     int       `json:"failures"`
+	BlockedUntil time.Time `json:"blocked_until"`
+	Reason       string    `json:"reason,omitempty"`
+}
+
+type FailureRecord struct {
+	Failures        int
+	Slowdown        time.Duration
+	ReachedSlowdown bool
+	Blocked         bool
+}
+
+type CleanupStats struct {
+	ActiveIPs      int
+	ActiveSubnets  int
+	RemovedIPs     int
+	RemovedSubnets int
+}
+
+// SecurityStore keeps the brute-force protection state. subnet is the
+// escalation key for ip and is empty when subnet blocking does not apply.
+type SecurityStore interface {
+	RecordFailure(ctx context.Context, ip, subnet string, now time.Time) (FailureRecord, error)
+	IsBlocked(ctx context.Context, ip, subnet string, now time.Time) (bool, error)
+	Slowdown(ctx context.Context, ip string) (time.Duration, error)
+	Reset(ctx context.Context, ip string) (bool, error)
+	Cleanup(ctx context.Context, now time.Time) (CleanupStats, error)
+	ListBlocks(ctx context.Context, now time.Time) ([]BlockEntry, error)
+	Block(ctx context.Context, kind, target string, until time.Time, reason string) error
+	Unblock(ctx context.Context, kind, target string) (bool, error)
+}
+
+type failurePolicy struct {
+	maxFailures   int
+	failWindow    time.Duration
+	blockDuration time.Duration
+	slowdownStep  time.Duration
+	slowdownMax   time.Duration
+}
+
+func newFailurePolicy(secCfg config.ConfSecurity) failurePolicy {
+	return failurePolicy{
+		maxFailures:   secCfg.MaxFailures,
+		failWindow:    secCfg.FailWindow,
+		blockDuration: secCfg.BlockDuration,
+		slowdownStep:  secCfg.SlowdownStep,
+		slowdownMax:   secCfg.SlowdownMax,
+	}
+}
+
+func (p failurePolicy) apply(state *ipState, now time.Time) FailureRecord {
+	if now.Sub(state.lastSeen) > p.failWindow {
+		state.failures = resetFailuresTo
+	}
+	state.failures++
+	state.lastSeen = now
+
+	var record FailureRecord
+	if state.slowdown < p.slowdownMax {
+		state.slowdown += p.slowdownStep
+		record.ReachedSlowdown = state.slowdown == p.slowdownMax
+	}
+
+	if state.failures >= p.maxFailures {
+		state.blockedUntil = now.Add(p.blockDuration)
+		state.reason = autoBlockReason
+		record.Blocked = true
+	}
+
+	record.Failures = state.failures
+	record.Slowdown = state.slowdown
+	return record
+}
+
+func (p failurePolicy) expired(state *ipState, now time.Time) bool {
+	return now.Sub(state.lastSeen) > p.failWindow*time.Duration(cleanupMultiplier) && !now.Before(state.blockedUntil)
+}
+
+type memorySecurityStore struct {
+	ipFailures map[string]*ipState
+	ipBlocks   map[string]subnetBlock
+	mu         sync.Mutex
+	policy     failurePolicy
+}
+
+func NewMemorySecurityStore(secCfg config.ConfSecurity) SecurityStore {
+	return &memorySecurityStore{
+		ipFailures: make(map[string]*ipState),
+		ipBlocks:   make(map[string]subnetBlock),
+		policy:     newFailurePolicy(secCfg),
+	}
+}
+
+func (s *memorySecurityStore) RecordFailure(_ context.Context, ip, subnet string, now time.Time) (FailureRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, exists := s.ipFailures[ip]
+	if !exists {
+		state = &ipState{}
+		s.ipFailures[ip] = state
+	}
+
+	record := s.policy.apply(state, now)
+	if record.Blocked && subnet != constants.EmptyString {
+		s.ipBlocks[subnet] = subnetBlock{until: now.Add(s.policy.blockDuration), reason: autoBlockReason}
+	}
+	return record, nil
+}
+
+func (s *memorySecurityStore) IsBlocked(_ context.Context, ip, subnet string, now time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if state, ok := s.ipFailures[ip]; ok && now.Before(state.blockedUntil) {
+		return true, nil
+	}
+	if subnet == constants.EmptyString {
+		return false, nil
+	}
+	block, blocked := s.ipBlocks[subnet]
+	return blocked && now.Before(block.until), nil
+}
+
+func (s *memorySecurityStore) Slowdown(_ context.Context, ip string) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if state, ok := s.ipFailures[ip]; ok {
+		return state.slowdown, nil
+	}
+	return time.Duration(resetFailuresTo), nil
+}
+
+func (s *memorySecurityStore) Reset(_ context.Context, ip string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.ipFailures[ip]; !exists {
+		return false, nil
+	}
+	delete(s.ipFailures, ip)
+	return true, nil
+}
+
+func (s *memorySecurityStore) Cleanup(_ context.Context, now time.Time) (CleanupStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ipCountBefore := len(s.ipFailures)
+	subnetCountBefore := len(s.ipBlocks)
+
+	for ip, state := range s.ipFailures {
+		if s.policy.expired(state, now) {
+			delete(s.ipFailures, ip)
+		}
+	}
+	for subnet, block := range s.ipBlocks {
+		if block.until.Before(now) {
+			delete(s.ipBlocks, subnet)
+		}
+	}
+
+	return CleanupStats{
+		ActiveIPs:      len(s.ipFailures),
+		ActiveSubnets:  len(s.ipBlocks),
+		RemovedIPs:     ipCountBefore - len(s.ipFailures),
+		RemovedSubnets: subnetCountBefore - len(s.ipBlocks),
+	}, nil
+}
+
+func (s *memorySecurityStore) ListBlocks(_ context.Context, now time.Time) ([]BlockEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := make([]BlockEntry, 0)
+	for ip, state := range s.ipFailures {
+		if now.Before(state.blockedUntil) {
+			entries = append(entries, BlockEntry{
+				Target:       ip,
+				Kind:         BlockKindIP,
+				Failures:     state.failures,
+				BlockedUntil: state.blockedUntil,
+				Reason:       state.reason,
+			})
+		}
+	}
+	for subnet, block := range s.ipBlocks {
+		if now.Before(block.until) {
+			entries = append(entries, BlockEntry{
+				Target:       subnet,
+				Kind:         BlockKindSubnet,
+				BlockedUntil: block.until,
+				Reason:       block.reason,
+			})
+		}
+	}
+	return entries, nil
+}
+
+func (s *memorySecurityStore) Block(_ context.Context, kind, target string, until time.Time, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if kind == BlockKindSubnet {
+		s.ipBlocks[target] = subnetBlock{until: until, reason: reason}
+		return nil
+	}
+
+	state, exists := s.ipFailures[target]
+	if !exists {
+		state = &ipState{lastSeen: time.Now()}
+		s.ipFailures[target] = state
+	}
+	state.blockedUntil = until
+	state.reason = reason
+	return nil
+}
+
+func (s *memorySecurityStore) Unblock(_ context.Context, kind, target string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if kind == BlockKindSubnet {
+		_, exists := s.ipBlocks[target]
+		delete(s.ipBlocks, target)
+		return exists, nil
+	}
+
+	_, exists := s.ipFailures[target]
+	delete(s.ipFailures, target)
+	return exists, nil
+}
@@ -0,0 +1,200 @@
+package middleware
+
+import (
+	"context"
+	"production-go-api-template/config"
+	"production-go-api-template/pkg/constants"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SecurityIPState struct {
+	IP           string    `gorm:"primaryKey;size:64"`
+	Failures     int       `gorm:"not null"`
+	BlockedUntil time.Time `gorm:"index"`
+	LastSeen     time.Time `gorm:"index"`
+	Slowdown     time.Duration
+	Reason       string `gorm:"size:255"`
+}
+
+type SecuritySubnetBlock struct {
+	Subnet       string    `gorm:"primaryKey;size:64"`
+	BlockedUntil time.Time `gorm:"index"`
+	Reason       string    `gorm:"size:255"`
+}
+
+// gormSecurityStore writes and compares every time in UTC: SQLite stores
+// them as text, and replicas sharing the store may run in different zones.
+type gormSecurityStore struct {
+	db     *gorm.DB
+	policy failurePolicy
+}
+
+func NewGormSecurityStore(db *gorm.DB, secCfg config.ConfSecurity) (SecurityStore, error) {
+	if err := db.AutoMigrate(&SecurityIPState{}, &SecuritySubnetBlock{}); err != nil {
+		return nil, err
+	}
+	return &gormSecurityStore{db: db, policy: newFailurePolicy(secCfg)}, nil
+}
+
+func (s *gormSecurityStore) RecordFailure(ctx context.Context, ip, subnet string, now time.Time) (FailureRecord, error) {
+	now = now.UTC()
+	var record FailureRecord
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var row SecurityIPState
+		if err := tx.Where("ip = ?", ip).Limit(1).Find(&row).Error; err != nil {
+			return err
+		}
+		row.IP = ip
+
+		state := &ipState{
+			failures:     row.Failures,
+			blockedUntil: row.BlockedUntil.UTC(),
+			lastSeen:     row.LastSeen.UTC(),
+			slowdown:     row.Slowdown,
+			reason:       row.Reason,
+		}
+		record = s.policy.apply(state, now)
+
+		row.Failures = state.failures
+		row.BlockedUntil = state.blockedUntil
+		row.LastSeen = state.lastSeen
+		row.Slowdown = state.slowdown
+		row.Reason = state.reason
+		if err := tx.Save(&row).Error; err != nil {
+			return err
+		}
+
+		if record.Blocked && subnet != constants.EmptyString {
+			block := SecuritySubnetBlock{Subnet: subnet, BlockedUntil: now.Add(s.policy.blockDuration), Reason: autoBlockReason}
+			return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&block).Error
+		}
+		return nil
+	})
+	return record, err
+}
+
+func (s *gormSecurityStore) IsBlocked(ctx context.Context, ip, subnet string, now time.Time) (bool, error) {
+	now = now.UTC()
+	db := s.db.WithContext(ctx)
+
+	var count int64
+	if err := db.Model(&SecurityIPState{}).Where("ip = ? AND blocked_until > ?", ip, now).Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 || subnet == constants.EmptyString {
+		return count > 0, nil
+	}
+
+	if err := db.Model(&SecuritySubnetBlock{}).Where("subnet = ? AND blocked_until > ?", subnet, now).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (s *gormSecurityStore) Slowdown(ctx context.Context, ip string) (time.Duration, error) {
+	var row SecurityIPState
+	if err := s.db.WithContext(ctx).Where("ip = ?", ip).Limit(1).Find(&row).Error; err != nil {
+		return time.Duration(resetFailuresTo), err
+	}
+	return row.Slowdown, nil
+}
+
+func (s *gormSecurityStore) Reset(ctx context.Context, ip string) (bool, error) {
+	result := s.db.WithContext(ctx).Where("ip = ?", ip).Delete(&SecurityIPState{})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (s *gormSecurityStore) Cleanup(ctx context.Context, now time.Time) (CleanupStats, error) {
+	now = now.UTC()
+	var stats CleanupStats
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		retention := s.policy.failWindow * time.Duration(cleanupMultiplier)
+		ips := tx.Where("last_seen < ? AND blocked_until <= ?", now.Add(-retention), now).Delete(&SecurityIPState{})
+		if ips.Error != nil {
+			return ips.Error
+		}
+		subnets := tx.Where("blocked_until < ?", now).Delete(&SecuritySubnetBlock{})
+		if subnets.Error != nil {
+			return subnets.Error
+		}
+		stats.RemovedIPs = int(ips.RowsAffected)
+		stats.RemovedSubnets = int(subnets.RowsAffected)
+
+		var activeIPs, activeSubnets int64
+		if err := tx.Model(&SecurityIPState{}).Count(&activeIPs).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&SecuritySubnetBlock{}).Count(&activeSubnets).Error; err != nil {
+			return err
+		}
+		stats.ActiveIPs = int(activeIPs)
+		stats.ActiveSubnets = int(activeSubnets)
+		return nil
+	})
+	return stats, err
+}
+
+func (s *gormSecurityStore) ListBlocks(ctx context.Context, now time.Time) ([]BlockEntry, error) {
+	now = now.UTC()
+	db := s.db.WithContext(ctx)
+
+	var ips []SecurityIPState
+	if err := db.Where("blocked_until > ?", now).Find(&ips).Error; err != nil {
+		return nil, err
+	}
+	var subnets []SecuritySubnetBlock
+	if err := db.Where("blocked_until > ?", now).Find(&subnets).Error; err != nil {
+		return nil, err
+	}
+
+	entries := make([]BlockEntry, 0, len(ips)+len(subnets))
+	for _, row := range ips {
+		entries = append(entries, BlockEntry{
+			Target:       row.IP,
+			Kind:         BlockKindIP,
+			Failures:     row.Failures,
+			BlockedUntil: row.BlockedUntil,
+			Reason:       row.Reason,
+		})
+	}
+	for _, row := range subnets {
+		entries = append(entries, BlockEntry{
+			Target:       row.Subnet,
+			Kind:         BlockKindSubnet,
+			BlockedUntil: row.BlockedUntil,
+			Reason:       row.Reason,
+		})
+	}
+	return entries, nil
+}
+
+func (s *gormSecurityStore) Block(ctx context.Context, kind, target string, until time.Time, reason string) error {
+	until = until.UTC()
+	db := s.db.WithContext(ctx)
+
+	if kind == BlockKindSubnet {
+		block := SecuritySubnetBlock{Subnet: target, BlockedUntil: until, Reason: reason}
+		return db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&block).Error
+	}
+
+	row := SecurityIPState{IP: target, BlockedUntil: until, LastSeen: time.Now().UTC(), Reason: reason}
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "ip"}},
+		DoUpdates: clause.AssignmentColumns([]string{"blocked_until", "reason"}),
+	}).Create(&row).Error
+}
+
+func (s *gormSecurityStore) Unblock(ctx context.Context, kind, target string) (bool, error) {
+	db := s.db.WithContext(ctx)
+
+	var result *gorm.DB
+	if kind == BlockKindSubnet {
+		result = db.Where("subnet = ?", target).Delete(&SecuritySubnetBlock{})
+	} else {
+		result = db.Where("ip = ?", target).Delete(&SecurityIPState{})
+	}
+	return result.RowsAffected > 0, result.Error
+}
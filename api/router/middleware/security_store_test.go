@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+func newGormTestStore(t *testing.T) SecurityStore {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "security.sqlite")), &gorm.Config{
+		Logger: gormlogger.Default.LogMode(gormlogger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewGormSecurityStore(db, testSecurityConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// sortedBlocks orders entries by target and normalises their time zone, so
+// the listings of different stores can be compared.
+func sortedBlocks(entries []BlockEntry) []BlockEntry {
+	for i := range entries {
+		entries[i].BlockedUntil = entries[i].BlockedUntil.UTC()
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Target < entries[j].Target })
+	return entries
+}
+
+// TestSecurityStores runs the same sequence of calls against every
+// SecurityStore implementation and expects identical results.
+func TestSecurityStores(t *testing.T) {
+	const (
+		ip       = "198.51.100.1"
+		neighbor = "198.51.100.2"
+		other    = "203.0.113.5"
+		subnet   = "198.51.100.0/24"
+	)
+	// testSecurityConfig: 3 failures within 1m block for 1h, slowdown grows
+	// by 1s up to 5s, and idle entries are cleaned up after 10m.
+	t0 := time.Now().Truncate(time.Second)
+	at := func(d time.Duration) time.Time { return t0.Add(d) }
+
+	type call func(ctx context.Context, s SecurityStore) (any, error)
+	type step struct {
+		name string
+		run  call
+		want any
+	}
+	record := func(ip, subnet string, d time.Duration) call {
+		return func(ctx context.Context, s SecurityStore) (any, error) {
+			return s.RecordFailure(ctx, ip, subnet, at(d))
+		}
+	}
+	blocked := func(ip, subnet string, d time.Duration) call {
+		return func(ctx context.Context, s SecurityStore) (any, error) { return s.IsBlocked(ctx, ip, subnet, at(d)) }
+	}
+	slowdown := func(ip string) call {
+		return func(ctx context.Context, s SecurityStore) (any, error) { return s.Slowdown(ctx, ip) }
+	}
+	list := func(d time.Duration) call {
+		return func(ctx context.Context, s SecurityStore) (any, error) {
+			entries, err := s.ListBlocks(ctx, at(d))
+			return sortedBlocks(entries), err
+		}
+	}
+
+	steps := []step{
+		{"first failure", record(ip, subnet, 0), FailureRecord{Failures: 1, Slowdown: time.Second}},
+		{"second failure", record(ip, subnet, 10*time.Second), FailureRecord{Failures: 2, Slowdown: 2 * time.Second}},
+		{"not blocked yet", blocked(ip, subnet, 15*time.Second), false},
+		{"slowdown of the IP", slowdown(ip), 2 * time.Second},
+		{"slowdown of an unknown IP", slowdown(other), time.Duration(0)},
+		{"third failure blocks", record(ip, subnet, 20*time.Second), FailureRecord{Failures: 3, Slowdown: 3 * time.Second, Blocked: true}},
+		{"IP blocked", blocked(ip, "", 30*time.Second), true},
+		{"neighbor blocked through the subnet", blocked(neighbor, subnet, 30*time.Second), true},
+		{"neighbor not blocked on its own", blocked(neighbor, "", 30*time.Second), false},
+		{"listed blocks", list(30 * time.Second), []BlockEntry{
+			{Target: subnet, Kind: BlockKindSubnet, BlockedUntil: at(20*time.Second + time.Hour).UTC(), Reason: autoBlockReason},
+			{Target: ip, Kind: BlockKindIP, Failures: 3, BlockedUntil: at(20*time.Second + time.Hour).UTC(), Reason: autoBlockReason},
+		}},
+		{"block expires", blocked(ip, subnet, 2*time.Hour), false},
+
+		{"failure of another IP", record(other, "", 0), FailureRecord{Failures: 1, Slowdown: time.Second}},
+		{"failures reset after the window", record(other, "", 2*time.Minute), FailureRecord{Failures: 1, Slowdown: 2 * time.Second}},
+		{"slowdown reaches its maximum", func(ctx context.Context, s SecurityStore) (any, error) {
+			for i := 0; i < 2; i++ {
+				if _, err := s.RecordFailure(ctx, other, "", at(3*time.Minute+time.Duration(i)*time.Hour)); err != nil {
+					return nil, err
+				}
+			}
+			return s.RecordFailure(ctx, other, "", at(3*time.Minute+2*time.Hour))
+		}, FailureRecord{Failures: 1, Slowdown: 5 * time.Second, ReachedSlowdown: true}},
+		{"reset", func(ctx context.Context, s SecurityStore) (any, error) { return s.Reset(ctx, other) }, true},
+		{"reset again", func(ctx context.Context, s SecurityStore) (any, error) { return s.Reset(ctx, other) }, false},
+		{"slowdown after reset", slowdown(other), time.Duration(0)},
+
+		{"manual IP block", func(ctx context.Context, s SecurityStore) (any, error) {
+			return nil, s.Block(ctx, BlockKindIP, other, at(time.Hour), "abuse")
+		}, nil},
+		{"manual subnet block", func(ctx context.Context, s SecurityStore) (any, error) {
+			return nil, s.Block(ctx, BlockKindSubnet, "203.0.113.0/24", at(time.Hour), "abuse")
+		}, nil},
+		{"manually blocked IP", blocked(other, "", time.Minute), true},
+		{"manual blocks listed", list(time.Minute), []BlockEntry{
+			{Target: subnet, Kind: BlockKindSubnet, BlockedUntil: at(20*time.Second + time.Hour).UTC(), Reason: autoBlockReason},
+			{Target: ip, Kind: BlockKindIP, Failures: 3, BlockedUntil: at(20*time.Second + time.Hour).UTC(), Reason: autoBlockReason},
+			{Target: "203.0.113.0/24", Kind: BlockKindSubnet, BlockedUntil: at(time.Hour).UTC(), Reason: "abuse"},
+			{Target: other, Kind: BlockKindIP, BlockedUntil: at(time.Hour).UTC(), Reason: "abuse"},
+		}},
+		{"unblock subnet", func(ctx context.Context, s SecurityStore) (any, error) {
+			return s.Unblock(ctx, BlockKindSubnet, "203.0.113.0/24")
+		}, true},
+		{"unblock missing subnet", func(ctx context.Context, s SecurityStore) (any, error) {
+			return s.Unblock(ctx, BlockKindSubnet, "203.0.113.0/24")
+		}, false},
+		{"unblock IP", func(ctx context.Context, s SecurityStore) (any, error) { return s.Unblock(ctx, BlockKindIP, other) }, true},
+		{"unblocked IP", blocked(other, "", time.Minute), false},
+
+		{"cleanup keeps live blocks", func(ctx context.Context, s SecurityStore) (any, error) { return s.Cleanup(ctx, at(time.Minute)) },
+			CleanupStats{ActiveIPs: 1, ActiveSubnets: 1}},
+		{"cleanup removes expired state", func(ctx context.Context, s SecurityStore) (any, error) { return s.Cleanup(ctx, at(3*time.Hour)) },
+			CleanupStats{RemovedIPs: 1, RemovedSubnets: 1}},
+		{"nothing listed after cleanup", list(3 * time.Hour), []BlockEntry{}},
+	}
+
+	stores := map[string]func(t *testing.T) SecurityStore{
+		"memory": func(*testing.T) SecurityStore { return NewMemorySecurityStore(testSecurityConfig()) },
+		"gorm":   newGormTestStore,
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			s := newStore(t)
+			for _, st := range steps {
+				got, err := st.run(context.Background(), s)
+				if err != nil {
+					t.Fatalf("%s: %v", st.name, err)
+				}
+				if !reflect.DeepEqual(got, st.want) {
+					t.Errorf("%s: got %+v, want %+v", st.name, got, st.want)
+				}
+			}
+		})
+	}
+}
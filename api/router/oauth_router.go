@@ -0,0 +1,41 @@
+package router
+
+import (
+	"net/http"
+	"production-go-api-template/api/resource/oauth"
+)
+
+type OAuthHandler struct {
+	Service *oauth.Service
+}
+
+func NewOAuthHandler(service *oauth.Service) *OAuthHandler {
+	return &OAuthHandler{Service: service}
+}
+
+func (h *OAuthHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /token", h.TokenHandler)
+	mux.HandleFunc("POST /introspect", h.IntrospectHandler)
+	mux.HandleFunc("POST /revoke", h.RevokeHandler)
+}
+
+func (h *OAuthHandler) TokenHandler(w http.ResponseWriter, r *http.Request) {
+	oauth.TokenHandler(h.Service, w, r)
+}
+
+func (h *OAuthHandler) IntrospectHandler(w http.ResponseWriter, r *http.Request) {
+	oauth.IntrospectHandler(h.Service, w, r)
+}
+
+func (h *OAuthHandler) RevokeHandler(w http.ResponseWriter, r *http.Request) {
+	oauth.RevokeHandler(h.Service, w, r)
+}
+
+func SetupOAuthRouter(service *oauth.Service) *http.ServeMux {
+	oauthRouter := http.NewServeMux()
+
+	h := NewOAuthHandler(service)
+	h.RegisterRoutes(oauthRouter)
+
+	return oauthRouter
+}
@@ -3,19 +3,48 @@ package router
 import (
 	"net/http"
 	"production-go-api-template/api/resource/health"
+	"production-go-api-template/api/resource/oauth"
+	"production-go-api-template/api/router/middleware"
+	auditlog "production-go-api-template/pkg/audit"
+	"production-go-api-template/pkg/auth"
 	"production-go-api-template/pkg/router"
 
 	"gorm.io/gorm"
 )
 
-func SetupRouter(db *gorm.DB) *http.ServeMux {
-	routerMux := http.NewServeMux()
+func SetupRouter(db *gorm.DB, authenticator *middleware.Authenticator, limiter *middleware.RateLimiter, oauthService *oauth.Service, auditLog *auditlog.Recorder) *router.Routes {
+	authenticated := authenticator.Middleware()
+	policies := map[router.AuthPolicy]func(http.Handler) http.Handler{}
 
-	routerMux.HandleFunc("GET /livez", health.NewHealthHandler().CheckHandler)
-	routerMux.HandleFunc("GET /healthz", health.HealthzHandler)
+	if limiter != nil {
+		limit := limiter.Middleware()
+		authenticated = middleware.CreateStack(authenticated, limit)
+		policies[router.PolicyPublic] = limit
+	}
+	policies[router.PolicyAuthenticated] = authenticated
+	policies[router.PolicyAdmin] = middleware.CreateStack(authenticated, authenticator.RequireAdmin(), middleware.RequireScopes(auth.ScopeAdmin))
+
+	routes := router.NewRoutes(policies)
+
+	routes.HandleFunc("GET /livez", router.PolicyPublic, health.NewHealthHandler().CheckHandler)
+	routes.HandleFunc("GET /healthz", router.PolicyPublic, health.HealthzHandler)
+	routes.HandleFunc("GET /time", router.PolicyPublic, health.TimeHandler)
 
 	itemsRouter := SetupItemRouter(db)
-	router.Mount(routerMux, "/api/v1/items", itemsRouter)
+	routes.Mount("/api/v1/items", router.PolicyAuthenticated, itemsRouter)
+
+	securityRouter := SetupSecurityRouter(authenticator)
+	routes.Mount("/api/v1/admin", router.PolicyAdmin, securityRouter)
+
+	if auditLog != nil {
+		auditRouter := SetupAuditRouter(auditLog)
+		routes.Mount("/api/v1/admin/audit", router.PolicyAdmin, auditRouter)
+	}
+
+	if oauthService != nil {
+		oauthRouter := SetupOAuthRouter(oauthService)
+		routes.Mount("/oauth", router.PolicyPublic, authenticator.Guard()(oauthRouter))
+	}
 
-	return routerMux
+	return routes
 }
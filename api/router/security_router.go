@@ -0,0 +1,41 @@
+package router
+
+import (
+	"net/http"
+	"production-go-api-template/api/resource/security"
+)
+
+type SecurityHandler struct {
+	Blocks security.BlockManager
+}
+
+func NewSecurityHandler(blocks security.BlockManager) *SecurityHandler {
+	return &SecurityHandler{Blocks: blocks}
+}
+
+func (h *SecurityHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /blocks", h.ListBlocksHandler)
+	mux.HandleFunc("POST /blocks", h.CreateBlockHandler)
+	mux.HandleFunc("DELETE /blocks/{target...}", h.DeleteBlockHandler)
+}
+
+func (h *SecurityHandler) ListBlocksHandler(w http.ResponseWriter, r *http.Request) {
+	security.ListBlocksHandler(h.Blocks, w, r)
+}
+
+func (h *SecurityHandler) CreateBlockHandler(w http.ResponseWriter, r *http.Request) {
+	security.CreateBlockHandler(h.Blocks, w, r)
+}
+
+func (h *SecurityHandler) DeleteBlockHandler(w http.ResponseWriter, r *http.Request) {
+	security.DeleteBlockHandler(h.Blocks, w, r)
+}
+
+func SetupSecurityRouter(blocks security.BlockManager) *http.ServeMux {
+	securityRouter := http.NewServeMux()
+
+	h := NewSecurityHandler(blocks)
+	h.RegisterRoutes(securityRouter)
+
+	return securityRouter
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+const usage = `apictl - API administration and debugging tool
+
+Usage:
+  apictl generate [flags]   Generate an API token and HMAC secret
+  apictl sign [flags]       Compute the signature headers for a request
+  apictl verify [flags]     Check a signature against a token and secret
+  apictl send [flags]       Send a signed request to a running server
+
+Run "apictl <command> -h" for the flags of a command.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	commands := map[string]func(args []string) error{
+		"generate": runGenerate,
+		"sign":     runSign,
+		"verify":   runVerify,
+		"send":     runSend,
+	}
+
+	run, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	if err := run(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "apictl %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"production-go-api-template/pkg/auth"
+	"production-go-api-template/pkg/client"
+	"sort"
+	"strings"
+	"time"
+)
+
+func runSend(args []string) error {
+	fs := flag.NewFlagSet("send", flag.ExitOnError)
+	baseURL := fs.String("url", "http://localhost:8080", "base URL of the server")
+	token := fs.String("token", os.Getenv("API_TOKEN"), "API access token (default $API_TOKEN)")
+	secret := fs.String("secret", os.Getenv("SECRET"), "HMAC secret (default $SECRET)")
+	method := fs.String("method", "GET", "HTTP method")
+	path := fs.String("path", "", "request path including an optional query string")
+	version := fs.String("version", auth.SignatureV1, "signature version, v1 or v2")
+	keyID := fs.String("key-id", "", "value of the X-Key-Id header")
+	nonce := fs.Bool("nonce", false, "send a random X-Nonce")
+	body := fs.String("body", "", "JSON request body")
+	timeout := fs.Duration("timeout", 30*time.Second, "request timeout")
+	_ = fs.Parse(args)
+
+	if *token == "" || *secret == "" || *path == "" {
+		return errors.New("-token, -secret and -path are required")
+	}
+
+	u, err := url.Parse(*path)
+	if err != nil {
+		return fmt.Errorf("invalid path: %w", err)
+	}
+
+	c, err := client.New(*baseURL, *token, *secret)
+	if err != nil {
+		return err
+	}
+	if *version == auth.SignatureV2 {
+		c.WithSignatureV2()
+	}
+	if *keyID != "" {
+		c.WithKeyID(*keyID)
+	}
+	if *nonce {
+		c.WithNonces()
+	}
+
+	var payload []byte
+	if *body != "" {
+		payload = []byte(*body)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	resp, err := c.Send(ctx, strings.ToUpper(*method), u.Path, u.Query(), payload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	fmt.Printf("%s %s\n", resp.Proto, resp.Status)
+	names := make([]string, 0, len(resp.Header))
+	for name := range resp.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("%s: %s\n", name, strings.Join(resp.Header[name], ", "))
+	}
+	fmt.Println()
+
+	if _, err := io.Copy(os.Stdout, resp.Body); err != nil {
+		return err
+	}
+	fmt.Println()
+	return nil
+}
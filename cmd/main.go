@@ -2,17 +2,26 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"production-go-api-template/api/resource"
+	"production-go-api-template/api/resource/item"
+	"production-go-api-template/api/resource/oauth"
 	"production-go-api-template/api/router"
 	"production-go-api-template/api/router/middleware"
 	"production-go-api-template/config"
+	"production-go-api-template/pkg/audit"
+	"production-go-api-template/pkg/auth"
+	"production-go-api-template/pkg/clientip"
 	"production-go-api-template/pkg/logger"
 
 	"github.com/rs/zerolog"
@@ -38,32 +47,98 @@ func main() {
 	db := openDatabase(c.DB.DBPath, l, logLevel)
 	migrate(db, l)
 
-	mux := router.SetupRouter(db)
+	ipResolver, err := clientip.NewResolver(c.Server.TrustedProxies, c.Server.ClientIPHeader)
+	if err != nil {
+		l.Fatal().Err(err).Msg("Failed to parse trusted proxies")
+	}
 
 	stack := middleware.CreateStack(
 		middleware.RequestID,
+		middleware.ResolveClientIP(ipResolver),
 		middleware.InjectDeps(c, l),
 		middleware.CORS(c.Server.CorsOrigins),
 		middleware.ContentTypeJSON,
 		middleware.RequestLog(l),
 	)
 
-	authenticator := middleware.NewAuthenticator(c.Auth, c.Security, l).Middleware()
+	clients, err := auth.NewRegistryFromConfig(c.Auth)
+	if err != nil {
+		l.Fatal().Err(err).Msg("Failed to load API clients")
+	}
+
+	accessList, err := middleware.NewIPAccessList(c.Security.AllowCIDRs, c.Security.DenyCIDRs)
+	if err != nil {
+		l.Fatal().Err(err).Msg("Failed to parse IP access lists")
+	}
+
+	schemes, err := middleware.ParseSchemeTable(c.Auth.DefaultSchemes, c.Auth.RouteSchemes)
+	if err != nil {
+		l.Fatal().Err(err).Msg("Failed to parse authentication schemes")
+	}
+
+	jwtVerifier, err := auth.NewJWTVerifierFromConfig(c.Auth)
+	if err != nil {
+		l.Fatal().Err(err).Msg("Failed to load JWT verification keys")
+	}
+
+	authenticator := middleware.NewAuthenticator(c.Auth, clients, c.Security, l).
+		WithSecurityStore(securityStore(c, db, l, logLevel)).
+		WithAccessList(accessList)
+
+	var auditLog *audit.Recorder
+	if c.Audit.Enabled {
+		auditLog = auditRecorder(c, db, l, logLevel)
+		authenticator.WithAuditor(auditLog)
+	}
+
+	var verifiers []middleware.TokenVerifier
+	if jwtVerifier != nil {
+		verifiers = append(verifiers, jwtVerifier)
+	}
+
+	var oauthService *oauth.Service
+	if c.OAuth.Enabled {
+		oauthService = oauth.NewService(c.OAuth, clients, authenticator, l).
+			WithRevocationStore(revocationStore(c, db, l, logLevel))
+		authenticator.WithClientTokens(oauthService)
+	}
+
+	if schemes.Uses(middleware.SchemeJWT) && jwtVerifier == nil && oauthService == nil {
+		l.Fatal().Msg("JWT authentication is enabled but neither JWT keys nor OAuth are configured")
+	}
+	if c.OAuth.Enabled && !schemes.Uses(middleware.SchemeJWT) {
+		l.Fatal().Msg("OAuth is enabled but no route accepts its tokens; add jwt to AUTH_DEFAULT_SCHEMES or AUTH_ROUTE_SCHEMES")
+	}
+	if schemes.Uses(middleware.SchemeMTLS) && c.Server.TLSClientCA == "" {
+		l.Fatal().Msg("mTLS authentication is enabled but SERVER_TLS_CLIENT_CA_FILE is not set")
+	}
+	authenticator.WithSchemes(schemes, verifiers...)
 
-	finalHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path == "/healthz" || r.URL.Path == "/livez" {
-			stack(mux).ServeHTTP(w, r)
-		} else {
-			stack(authenticator(mux)).ServeHTTP(w, r)
+	var limiter *middleware.RateLimiter
+	if c.RateLimit.Enabled {
+		limiter, err = middleware.NewRateLimiter(c.RateLimit, clients, l)
+		if err != nil {
+			l.Fatal().Err(err).Msg("Failed to parse rate limits")
 		}
-	})
+	}
+
+	routes := router.SetupRouter(db, authenticator, limiter, oauthService, auditLog)
+	for _, route := range routes.Routes() {
+		l.Debug().Msgf("Registered route %s (%s)", route.Pattern, route.Policy)
+	}
+
+	tlsConfig, err := serverTLSConfig(c.Server)
+	if err != nil {
+		l.Fatal().Err(err).Msg("Failed to load the TLS client CA bundle")
+	}
 
 	s := &http.Server{
 		Addr:         fmt.Sprintf(":%d", c.Server.Port),
-		Handler:      finalHandler,
+		Handler:      stack(routes),
 		ReadTimeout:  c.Server.TimeoutRead,
 		WriteTimeout: c.Server.TimeoutWrite,
 		IdleTimeout:  c.Server.TimeoutIdle,
+		TLSConfig:    tlsConfig,
 	}
 
 	done := make(chan struct{})
@@ -71,11 +146,13 @@ func main() {
 	go func() {
 		defer close(done)
 		l.Info().Msgf("Starting server %v", s.Addr)
-		if err := s.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := listen(s, c.Server); err != nil && err != http.ErrServerClosed {
 			l.Fatal().Err(err).Msg("Server startup failure")
 		}
 	}()
 
+	go reloadOnHangup(accessList, l)
+
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
@@ -92,11 +169,24 @@ func main() {
 
 	<-done
 
+	if auditLog != nil {
+		auditLog.Close()
+	}
+
 	l.Info().Msgf("Server shutdown successfully")
 }
 
+// openDatabase waits for locks instead of failing at once, because the audit
+// recorder and the request handlers write to the same file concurrently.
+// Transactions take the write lock when they begin: one that read first could
+// not upgrade to writing while another connection writes, busy timeout or not.
 func openDatabase(path string, l *logger.Logger, gl gormlogger.LogLevel) *gorm.DB {
-	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{
+	dsn := path
+	if !strings.Contains(dsn, "?") {
+		dsn += "?_busy_timeout=5000&_txlock=immediate"
+	}
+
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
 		Logger: gormlogger.Default.LogMode(gl),
 	})
 	if err != nil {
@@ -105,8 +195,119 @@ func openDatabase(path string, l *logger.Logger, gl gormlogger.LogLevel) *gorm.D
 	return db
 }
 
+func serverTLSConfig(c config.ConfServer) (*tls.Config, error) {
+	if c.TLSClientCA == "" {
+		return nil, nil
+	}
+
+	pem, err := os.ReadFile(c.TLSClientCA)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("no certificates found in the client CA bundle")
+	}
+
+	clientAuth := tls.VerifyClientCertIfGiven
+	if c.TLSClientAuth == config.TLSClientAuthRequire {
+		clientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ClientCAs:  pool,
+		ClientAuth: clientAuth,
+	}, nil
+}
+
+func listen(s *http.Server, c config.ConfServer) error {
+	if c.TLSCertFile != "" {
+		return s.ListenAndServeTLS(c.TLSCertFile, c.TLSKeyFile)
+	}
+	return s.ListenAndServe()
+}
+
+func securityStore(c *config.Conf, db *gorm.DB, l *logger.Logger, gl gormlogger.LogLevel) middleware.SecurityStore {
+	if c.Security.Store == config.SecurityStoreMemory {
+		return middleware.NewMemorySecurityStore(c.Security)
+	}
+
+	if c.Security.StoreDBPath != "" && c.Security.StoreDBPath != c.DB.DBPath {
+		db = openDatabase(c.Security.StoreDBPath, l, gl)
+	}
+
+	store, err := middleware.NewGormSecurityStore(db, c.Security)
+	if err != nil {
+		l.Fatal().Err(err).Msg("Failed to initialize the security store")
+	}
+	return store
+}
+
+func revocationStore(c *config.Conf, db *gorm.DB, l *logger.Logger, gl gormlogger.LogLevel) oauth.RevocationStore {
+	if c.OAuth.RevocationStore == config.RevocationStoreMemory {
+		return oauth.NewMemoryRevocationStore()
+	}
+
+	if c.OAuth.RevocationDBPath != "" && c.OAuth.RevocationDBPath != c.DB.DBPath {
+		db = openDatabase(c.OAuth.RevocationDBPath, l, gl)
+	}
+
+	store, err := oauth.NewGormRevocationStore(db)
+	if err != nil {
+		l.Fatal().Err(err).Msg("Failed to initialize the token revocation store")
+	}
+	return store
+}
+
+func auditRecorder(c *config.Conf, db *gorm.DB, l *logger.Logger, gl gormlogger.LogLevel) *audit.Recorder {
+	if c.Audit.DBPath != "" && c.Audit.DBPath != c.DB.DBPath {
+		db = openDatabase(c.Audit.DBPath, l, gl)
+	}
+
+	store, err := audit.NewGormStore(db)
+	if err != nil {
+		l.Fatal().Err(err).Msg("Failed to initialize the audit store")
+	}
+
+	var sinks []audit.Sink
+	if c.Audit.LogFile != "" {
+		f, err := os.OpenFile(c.Audit.LogFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			l.Fatal().Err(err).Msg("Failed to open the audit log file")
+		}
+		sinks = append(sinks, audit.NewLogSink(f))
+	}
+
+	return audit.NewRecorder(c.Audit.BufferSize, store, c.Audit.Retention, l, sinks...)
+}
+
+func reloadOnHangup(accessList *middleware.IPAccessList, l *logger.Logger) {
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+
+	for range hupChan {
+		c, err := config.Reload()
+		if err != nil {
+			l.Error().Err(err).Msg("Config reload failed")
+			continue
+		}
+		if err := accessList.Update(c.Security.AllowCIDRs, c.Security.DenyCIDRs); err != nil {
+			l.Error().Err(err).Msg("Invalid IP access lists, keeping the previous lists")
+			continue
+		}
+		allowed, denied := accessList.Sizes()
+		l.Info().Msgf("Reloaded IP access lists: %d allowed, %d denied networks", allowed, denied)
+	}
+}
+
 func migrate(db *gorm.DB, l *logger.Logger) {
-	if err := resource.AutoMigrateAll(db); err != nil {
+	err := resource.AutoMigrateAll(db)
+	if errors.Is(err, item.ErrSearchUnavailable) {
+		l.Warn().Err(err).Msg("Item search is disabled and answers 501")
+		return
+	}
+	if err != nil {
 		l.Fatal().Err(err).Msg("Failed to migrate the database")
 	}
 }
@@ -1,9 +1,12 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"production-go-api-template/pkg/constants"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/joeshaw/envdecode"
@@ -11,33 +14,93 @@ import (
 )
 
 type Conf struct {
-	Server   ConfServer
-	Auth     ConfAuth
-	Security ConfSecurity
-	DB       ConfDB
+	Server    ConfServer
+	Auth      ConfAuth
+	Security  ConfSecurity
+	OAuth     ConfOAuth
+	Audit     ConfAudit
+	RateLimit ConfRateLimit
+	Items     ConfItems
+	DB        ConfDB
 }
 
 type ConfServer struct {
-	Port         int           `env:"SERVER_PORT,default=8080"`
-	TimeoutRead  time.Duration `env:"SERVER_TIMEOUT_READ,default=30s"`
-	TimeoutWrite time.Duration `env:"SERVER_TIMEOUT_WRITE,default=30s"`
-	TimeoutIdle  time.Duration `env:"SERVER_TIMEOUT_IDLE,default=60s"`
-	Debug        bool          `env:"SERVER_DEBUG,default=true"`
-	CorsOrigins  []string      `env:"SERVER_CORS_ORIGINS,default=*"`
+	Port           int           `env:"SERVER_PORT,default=8080"`
+	TimeoutRead    time.Duration `env:"SERVER_TIMEOUT_READ,default=30s"`
+	TimeoutWrite   time.Duration `env:"SERVER_TIMEOUT_WRITE,default=30s"`
+	TimeoutIdle    time.Duration `env:"SERVER_TIMEOUT_IDLE,default=60s"`
+	Debug          bool          `env:"SERVER_DEBUG,default=true"`
+	CorsOrigins    []string      `env:"SERVER_CORS_ORIGINS,default=*"`
+	TrustedProxies []string      `env:"SERVER_TRUSTED_PROXIES"`
+	ClientIPHeader string        `env:"SERVER_CLIENT_IP_HEADER,default=x-forwarded-for"`
+	TLSCertFile    string        `env:"SERVER_TLS_CERT_FILE"`
+	TLSKeyFile     string        `env:"SERVER_TLS_KEY_FILE"`
+	TLSClientCA    string        `env:"SERVER_TLS_CLIENT_CA_FILE"`
+	TLSClientAuth  string        `env:"SERVER_TLS_CLIENT_AUTH,default=optional"`
 }
 
 type ConfAuth struct {
-	APITokens   string `env:"API_TOKEN,required"`
-	HMACSecrets string `env:"SECRET,required"`
+	APITokens          string        `env:"API_TOKEN"`
+	HMACSecrets        string        `env:"SECRET"`
+	ClientsFile        string        `env:"AUTH_CLIENTS_FILE"`
+	SignatureV1Enabled bool          `env:"AUTH_SIGNATURE_V1_ENABLED,default=true"`
+	NonceRequired      bool          `env:"AUTH_NONCE_REQUIRED,default=false"`
+	TimestampSkew      time.Duration `env:"AUTH_TIMESTAMP_SKEW,default=5m"`
+	DefaultSchemes     string        `env:"AUTH_DEFAULT_SCHEMES,default=signature"`
+	RouteSchemes       []string      `env:"AUTH_ROUTE_SCHEMES"`
+	JWTJWKSFile        string        `env:"AUTH_JWT_JWKS_FILE"`
+	JWTHS256Secret     string        `env:"AUTH_JWT_HS256_SECRET"`
+	JWTIssuer          string        `env:"AUTH_JWT_ISSUER"`
+	JWTAudience        string        `env:"AUTH_JWT_AUDIENCE"`
+	JWTLeeway          time.Duration `env:"AUTH_JWT_LEEWAY,default=30s"`
 }
 
 type ConfSecurity struct {
-	MaxFailures   int           `env:"SECURITY_MAX_FAILURES,default=5"`
-	FailWindow    time.Duration `env:"SECURITY_FAIL_WINDOW,default=1m"`
-	BlockDuration time.Duration `env:"SECURITY_BLOCK_DURATION,default=10m"`
-	CleanupTick   time.Duration `env:"SECURITY_CLEANUP_TICK,default=5m"`
-	SlowdownStep  time.Duration `env:"SECURITY_SLOWDOWN_STEP,default=200ms"`
-	SlowdownMax   time.Duration `env:"SECURITY_SLOWDOWN_MAX,default=2s"`
+	MaxFailures           int           `env:"SECURITY_MAX_FAILURES,default=5"`
+	FailWindow            time.Duration `env:"SECURITY_FAIL_WINDOW,default=1m"`
+	BlockDuration         time.Duration `env:"SECURITY_BLOCK_DURATION,default=10m"`
+	CleanupTick           time.Duration `env:"SECURITY_CLEANUP_TICK,default=5m"`
+	SlowdownStep          time.Duration `env:"SECURITY_SLOWDOWN_STEP,default=200ms"`
+	SlowdownMax           time.Duration `env:"SECURITY_SLOWDOWN_MAX,default=2s"`
+	SlowdownMode          string        `env:"SECURITY_SLOWDOWN_MODE,default=delay"`
+	SlowdownMaxConcurrent int           `env:"SECURITY_SLOWDOWN_MAX_CONCURRENT,default=100"`
+	NonceCacheSize        int           `env:"SECURITY_NONCE_CACHE_SIZE,default=100000"`
+	SubnetBlocking        bool          `env:"SECURITY_SUBNET_BLOCKING,default=true"`
+	SubnetPrefixV4        int           `env:"SECURITY_SUBNET_PREFIX_V4,default=24"`
+	SubnetPrefixV6        int           `env:"SECURITY_SUBNET_PREFIX_V6,default=64"`
+	Store                 string        `env:"SECURITY_STORE,default=memory"`
+	StoreDBPath           string        `env:"SECURITY_STORE_DB_PATH"`
+	AllowCIDRs            []string      `env:"SECURITY_ALLOW_CIDRS"`
+	DenyCIDRs             []string      `env:"SECURITY_DENY_CIDRS"`
+}
+
+type ConfOAuth struct {
+	Enabled          bool          `env:"OAUTH_ENABLED,default=false"`
+	SigningSecret    string        `env:"OAUTH_SIGNING_SECRET"`
+	Issuer           string        `env:"OAUTH_ISSUER,default=production-go-api-template"`
+	Audience         string        `env:"OAUTH_AUDIENCE,default=production-go-api-template"`
+	TokenTTL         time.Duration `env:"OAUTH_TOKEN_TTL,default=15m"`
+	RevocationStore  string        `env:"OAUTH_REVOCATION_STORE,default=sqlite"`
+	RevocationDBPath string        `env:"OAUTH_REVOCATION_DB_PATH"`
+}
+
+type ConfAudit struct {
+	Enabled    bool          `env:"AUDIT_ENABLED,default=true"`
+	DBPath     string        `env:"AUDIT_DB_PATH"`
+	LogFile    string        `env:"AUDIT_LOG_FILE"`
+	Retention  time.Duration `env:"AUDIT_RETENTION,default=720h"`
+	BufferSize int           `env:"AUDIT_BUFFER_SIZE,default=1000"`
+}
+
+type ConfRateLimit struct {
+	Enabled   bool     `env:"RATE_LIMIT_ENABLED,default=true"`
+	Default   string   `env:"RATE_LIMIT_DEFAULT,default=600/1m"`
+	Routes    []string `env:"RATE_LIMIT_ROUTES"`
+	CacheSize int      `env:"RATE_LIMIT_CACHE_SIZE,default=100000"`
+}
+
+type ConfItems struct {
+	RequireIfMatch bool `env:"ITEMS_REQUIRE_IF_MATCH,default=false"`
 }
 
 type ConfDB struct {
@@ -47,22 +110,172 @@ type ConfDB struct {
 
 const (
 	defaultDotenv = ".env"
+	maxPrefixV4   = 32
+	maxPrefixV6   = 128
+
+	minSigningSecretLength = 32
+
+	SecurityStoreMemory = "memory"
+	SecurityStoreSQLite = "sqlite"
+
+	RevocationStoreMemory = "memory"
+	RevocationStoreSQLite = "sqlite"
+
+	SlowdownModeDelay  = "delay"
+	SlowdownModeReject = "reject"
+
+	TLSClientAuthOptional = "optional"
+	TLSClientAuthRequire  = "require"
+
+	ClientIPHeaderXForwardedFor = "x-forwarded-for"
+	ClientIPHeaderForwarded     = "forwarded"
+	ClientIPHeaderXRealIP       = "x-real-ip"
 )
 
+// dotenv remembers the environment the process started with and the values
+// the dotenv file added to it, so a reload can drop keys that were removed
+// from the file instead of keeping their old values.
+var dotenv struct {
+	mu      sync.Mutex
+	startup map[string]string
+	values  map[string]string
+}
+
 func New() (*Conf, error) {
-	dotenvPath := os.Getenv("DOTENV_CONFIG_PATH")
-	if dotenvPath == constants.EmptyString {
-		dotenvPath = defaultDotenv
+	values, err := godotenv.Read(dotenvPath())
+	if err != nil {
+		return nil, fmt.Errorf("dotenv load failed: %w", err)
 	}
 
-	if err := godotenv.Load(dotenvPath); err != nil {
-		return nil, fmt.Errorf("dotenv load failed: %w", err)
+	dotenv.mu.Lock()
+	dotenv.startup = environ()
+	for key, value := range values {
+		// Like godotenv.Load, the process environment wins at startup.
+		if _, set := dotenv.startup[key]; !set {
+			_ = os.Setenv(key, value)
+		}
+	}
+	dotenv.values = values
+	dotenv.mu.Unlock()
+
+	return decode()
+}
+
+// Reload re-reads the dotenv file and layers it over the startup
+// environment, so settings such as the IP access lists can change without a
+// restart. Keys deleted from the file fall back to their startup value or
+// become unset.
+func Reload() (*Conf, error) {
+	values, err := godotenv.Read(dotenvPath())
+	if err != nil {
+		return nil, fmt.Errorf("dotenv reload failed: %w", err)
+	}
+
+	dotenv.mu.Lock()
+	for key := range dotenv.values {
+		if _, kept := values[key]; kept {
+			continue
+		}
+		if value, ok := dotenv.startup[key]; ok {
+			_ = os.Setenv(key, value)
+		} else {
+			_ = os.Unsetenv(key)
+		}
+	}
+	for key, value := range values {
+		_ = os.Setenv(key, value)
 	}
+	dotenv.values = values
+	dotenv.mu.Unlock()
 
+	return decode()
+}
+
+func environ() map[string]string {
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if key, value, ok := strings.Cut(kv, "="); ok {
+			env[key] = value
+		}
+	}
+	return env
+}
+
+func dotenvPath() string {
+	path := os.Getenv("DOTENV_CONFIG_PATH")
+	if path == constants.EmptyString {
+		return defaultDotenv
+	}
+	return path
+}
+
+func decode() (*Conf, error) {
 	var c Conf
 	if err := envdecode.StrictDecode(&c); err != nil {
 		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 
+	if err := c.Server.validate(); err != nil {
+		return nil, fmt.Errorf("invalid server config: %w", err)
+	}
+	if err := c.Security.validate(); err != nil {
+		return nil, fmt.Errorf("invalid security config: %w", err)
+	}
+	if c.Auth.TimestampSkew < time.Second {
+		return nil, errors.New("AUTH_TIMESTAMP_SKEW must be at least 1s")
+	}
+	if c.Audit.Enabled && c.Audit.BufferSize <= constants.ZeroIndex {
+		return nil, errors.New("AUDIT_BUFFER_SIZE must be positive")
+	}
+	if c.RateLimit.Enabled && c.RateLimit.CacheSize <= constants.ZeroIndex {
+		return nil, errors.New("RATE_LIMIT_CACHE_SIZE must be positive")
+	}
+	if c.OAuth.Enabled && len(c.OAuth.SigningSecret) < minSigningSecretLength {
+		return nil, fmt.Errorf("OAUTH_SIGNING_SECRET must be at least %d characters", minSigningSecretLength)
+	}
+	if c.OAuth.RevocationStore != RevocationStoreMemory && c.OAuth.RevocationStore != RevocationStoreSQLite {
+		return nil, fmt.Errorf("OAUTH_REVOCATION_STORE must be %q or %q", RevocationStoreMemory, RevocationStoreSQLite)
+	}
+
 	return &c, nil
 }
+
+func (s ConfServer) validate() error {
+	if (s.TLSCertFile == constants.EmptyString) != (s.TLSKeyFile == constants.EmptyString) {
+		return errors.New("SERVER_TLS_CERT_FILE and SERVER_TLS_KEY_FILE must be set together")
+	}
+	if s.TLSClientCA != constants.EmptyString && s.TLSCertFile == constants.EmptyString {
+		return errors.New("SERVER_TLS_CLIENT_CA_FILE requires SERVER_TLS_CERT_FILE and SERVER_TLS_KEY_FILE")
+	}
+	if s.TLSClientAuth != TLSClientAuthOptional && s.TLSClientAuth != TLSClientAuthRequire {
+		return fmt.Errorf("SERVER_TLS_CLIENT_AUTH must be %q or %q", TLSClientAuthOptional, TLSClientAuthRequire)
+	}
+	switch s.ClientIPHeader {
+	case ClientIPHeaderXForwardedFor, ClientIPHeaderForwarded, ClientIPHeaderXRealIP:
+	default:
+		return fmt.Errorf("SERVER_CLIENT_IP_HEADER must be %q, %q or %q", ClientIPHeaderXForwardedFor, ClientIPHeaderForwarded, ClientIPHeaderXRealIP)
+	}
+	return nil
+}
+
+func (s ConfSecurity) validate() error {
+	if s.SubnetPrefixV4 < constants.ZeroIndex || s.SubnetPrefixV4 > maxPrefixV4 {
+		return fmt.Errorf("SECURITY_SUBNET_PREFIX_V4 must be between 0 and %d", maxPrefixV4)
+	}
+	if s.SubnetPrefixV6 < constants.ZeroIndex || s.SubnetPrefixV6 > maxPrefixV6 {
+		return fmt.Errorf("SECURITY_SUBNET_PREFIX_V6 must be between 0 and %d", maxPrefixV6)
+	}
+	if s.Store != SecurityStoreMemory && s.Store != SecurityStoreSQLite {
+		return fmt.Errorf("SECURITY_STORE must be %q or %q", SecurityStoreMemory, SecurityStoreSQLite)
+	}
+	if s.SlowdownMode != SlowdownModeDelay && s.SlowdownMode != SlowdownModeReject {
+		return fmt.Errorf("SECURITY_SLOWDOWN_MODE must be %q or %q", SlowdownModeDelay, SlowdownModeReject)
+	}
+	if s.SlowdownMaxConcurrent <= constants.ZeroIndex {
+		return errors.New("SECURITY_SLOWDOWN_MAX_CONCURRENT must be positive; use SECURITY_SLOWDOWN_MODE=reject to never wait")
+	}
+	if s.NonceCacheSize <= constants.ZeroIndex {
+		return errors.New("SECURITY_NONCE_CACHE_SIZE must be positive")
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeDotenv(t *testing.T, path string, lines ...string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReloadClearsRemovedKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	t.Setenv("DOTENV_CONFIG_PATH", path)
+	t.Setenv("SERVER_PORT", "9090")
+	for _, key := range []string{"SECURITY_ALLOW_CIDRS", "SECURITY_DENY_CIDRS", "SECURITY_MAX_FAILURES"} {
+		t.Cleanup(func() { _ = os.Unsetenv(key) })
+	}
+
+	writeDotenv(t, path,
+		"SERVER_PORT=8081",
+		"SECURITY_ALLOW_CIDRS=10.0.0.0/8",
+		"SECURITY_DENY_CIDRS=203.0.113.0/24;198.51.100.7",
+		"SECURITY_MAX_FAILURES=7",
+	)
+	c, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Server.Port != 9090 {
+		t.Errorf("startup: SERVER_PORT = %d, want the process environment to win", c.Server.Port)
+	}
+	if len(c.Security.DenyCIDRs) != 2 || c.Security.MaxFailures != 7 {
+		t.Fatalf("startup: deny = %v, max failures = %d", c.Security.DenyCIDRs, c.Security.MaxFailures)
+	}
+
+	writeDotenv(t, path,
+		"SECURITY_ALLOW_CIDRS=10.0.0.0/8;192.0.2.0/24",
+	)
+	c, err = Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(c.Security.AllowCIDRs) != 2 {
+		t.Errorf("reload: allow = %v, want the new list", c.Security.AllowCIDRs)
+	}
+	if len(c.Security.DenyCIDRs) != 0 {
+		t.Errorf("reload: deny = %v, want it cleared", c.Security.DenyCIDRs)
+	}
+	if c.Security.MaxFailures != 5 {
+		t.Errorf("reload: SECURITY_MAX_FAILURES = %d, want the default", c.Security.MaxFailures)
+	}
+	if c.Server.Port != 9090 {
+		t.Errorf("reload: SERVER_PORT = %d, want the startup value", c.Server.Port)
+	}
+}
+
+func TestReloadMissingFile(t *testing.T) {
+	t.Setenv("DOTENV_CONFIG_PATH", filepath.Join(t.TempDir(), "missing.env"))
+	if _, err := Reload(); err == nil {
+		t.Fatal("Reload() expected an error for a missing file")
+	}
+}
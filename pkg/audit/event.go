@@ -0,0 +1,67 @@
+package audit
+
+import (
+	"context"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventAuthSuccess    EventType = "auth_success"
+	EventBadToken       EventType = "bad_token"
+	EventBadSignature   EventType = "bad_signature"
+	EventBadCertificate EventType = "bad_certificate"
+	EventTimestampSkew  EventType = "timestamp_skew"
+	EventIPDenied       EventType = "ip_denied"
+	EventIPBlocked      EventType = "ip_blocked"
+	EventSubnetBlocked  EventType = "subnet_blocked"
+)
+
+var EventTypes = []EventType{
+	EventAuthSuccess,
+	EventBadToken,
+	EventBadSignature,
+	EventBadCertificate,
+	EventTimestampSkew,
+	EventIPDenied,
+	EventIPBlocked,
+	EventSubnetBlocked,
+}
+
+type Event struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	Time      time.Time `json:"time" gorm:"not null;index"`
+	Type      EventType `json:"type" gorm:"not null;size:32;index"`
+	ClientID  string    `json:"client_id,omitempty" gorm:"size:255;index"`
+	IP        string    `json:"ip" gorm:"size:64;index"`
+	Method    string    `json:"method,omitempty" gorm:"size:16"`
+	Route     string    `json:"route,omitempty" gorm:"size:1000"`
+	RequestID string    `json:"request_id,omitempty" gorm:"size:64"`
+	Detail    string    `json:"detail,omitempty" gorm:"size:1000"`
+}
+
+func (Event) TableName() string {
+	return "audit_events"
+}
+
+// Filter selects events for Query. Zero values match everything; From is
+// inclusive and To exclusive.
+type Filter struct {
+	From     time.Time
+	To       time.Time
+	Type     EventType
+	ClientID string
+	IP       string
+	Limit    int
+}
+
+type Sink interface {
+	Write(ctx context.Context, events []Event) error
+}
+
+type Store interface {
+	Sink
+	Query(ctx context.Context, filter Filter) ([]Event, error)
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
@@ -0,0 +1,34 @@
+package audit
+
+import (
+	"context"
+	"io"
+
+	"github.com/rs/zerolog"
+)
+
+type logSink struct {
+	log zerolog.Logger
+}
+
+// NewLogSink writes every event as one JSON line to w, separate from the
+// application log.
+func NewLogSink(w io.Writer) Sink {
+	return &logSink{log: zerolog.New(w)}
+}
+
+func (s *logSink) Write(_ context.Context, events []Event) error {
+	for _, e := range events {
+		s.log.Log().
+			Time("time", e.Time).
+			Str("type", string(e.Type)).
+			Str("client_id", e.ClientID).
+			Str("ip", e.IP).
+			Str("method", e.Method).
+			Str("route", e.Route).
+			Str("request_id", e.RequestID).
+			Str("detail", e.Detail).
+			Send()
+	}
+	return nil
+}
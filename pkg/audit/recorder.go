@@ -0,0 +1,120 @@
+package audit
+
+import (
+	"context"
+	"production-go-api-template/pkg/logger"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	maxBatchSize  = 100
+	purgeInterval = time.Hour
+)
+
+// Recorder hands events to its sinks from a background goroutine so audit
+// writes never delay a request. When the buffer is full events are dropped
+// and counted instead of blocking.
+type Recorder struct {
+	events    chan Event
+	sinks     []Sink
+	store     Store
+	retention time.Duration
+	dropped   atomic.Int64
+	done      chan struct{}
+	closeOnce sync.Once
+	log       *logger.Logger
+}
+
+func NewRecorder(bufferSize int, store Store, retention time.Duration, log *logger.Logger, sinks ...Sink) *Recorder {
+	if store != nil {
+		sinks = append([]Sink{store}, sinks...)
+	}
+
+	r := &Recorder{
+		events:    make(chan Event, bufferSize),
+		sinks:     sinks,
+		store:     store,
+		retention: retention,
+		done:      make(chan struct{}),
+		log:       log,
+	}
+	go r.run()
+
+	return r
+}
+
+func (r *Recorder) Record(e Event) {
+	select {
+	case r.events <- e:
+	default:
+		if r.dropped.Add(1) == 1 {
+			r.log.Warnf("Audit buffer full, dropping events")
+		}
+	}
+}
+
+func (r *Recorder) Query(ctx context.Context, filter Filter) ([]Event, error) {
+	if r.store == nil {
+		return nil, nil
+	}
+	return r.store.Query(ctx, filter)
+}
+
+// Close flushes buffered events and stops the background goroutine.
+func (r *Recorder) Close() {
+	r.closeOnce.Do(func() {
+		close(r.events)
+		<-r.done
+	})
+}
+
+func (r *Recorder) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+
+	batch := make([]Event, 0, maxBatchSize)
+	for {
+		select {
+		case e, ok := <-r.events:
+			if !ok {
+				return
+			}
+			batch = append(batch[:0], e)
+			for len(batch) < maxBatchSize && len(r.events) > 0 {
+				batch = append(batch, <-r.events)
+			}
+			r.write(batch)
+		case <-ticker.C:
+			r.purge()
+		}
+	}
+}
+
+func (r *Recorder) write(batch []Event) {
+	if dropped := r.dropped.Swap(0); dropped > 0 {
+		r.log.Warnf("Dropped %d audit events because the buffer was full", dropped)
+	}
+	for _, s := range r.sinks {
+		if err := s.Write(context.Background(), batch); err != nil {
+			r.log.Errorf("Failed to write %d audit events: %v", len(batch), err)
+		}
+	}
+}
+
+func (r *Recorder) purge() {
+	if r.store == nil || r.retention <= 0 {
+		return
+	}
+	removed, err := r.store.Purge(context.Background(), time.Now().Add(-r.retention))
+	if err != nil {
+		r.log.Errorf("Failed to purge audit events: %v", err)
+		return
+	}
+	if removed > 0 {
+		r.log.Infof("Purged %d audit events older than %s", removed, r.retention)
+	}
+}
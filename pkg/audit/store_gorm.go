@@ -0,0 +1,58 @@
+package audit
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type gormStore struct {
+	db *gorm.DB
+}
+
+func NewGormStore(db *gorm.DB) (Store, error) {
+	if err := db.AutoMigrate(&Event{}); err != nil {
+		return nil, err
+	}
+	return &gormStore{db: db}, nil
+}
+
+func (s *gormStore) Write(ctx context.Context, events []Event) error {
+	return s.db.WithContext(ctx).Create(&events).Error
+}
+
+// Query compares times in UTC because SQLite stores them as text and events
+// are recorded in UTC.
+func (s *gormStore) Query(ctx context.Context, filter Filter) ([]Event, error) {
+	q := s.db.WithContext(ctx).Model(&Event{})
+	if !filter.From.IsZero() {
+		q = q.Where("time >= ?", filter.From.UTC())
+	}
+	if !filter.To.IsZero() {
+		q = q.Where("time < ?", filter.To.UTC())
+	}
+	if filter.Type != "" {
+		q = q.Where("type = ?", filter.Type)
+	}
+	if filter.ClientID != "" {
+		q = q.Where("client_id = ?", filter.ClientID)
+	}
+	if filter.IP != "" {
+		q = q.Where("ip = ?", filter.IP)
+	}
+	if filter.Limit > 0 {
+		q = q.Limit(filter.Limit)
+	}
+
+	var events []Event
+	if err := q.Order("time DESC, id DESC").Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (s *gormStore) Purge(ctx context.Context, before time.Time) (int64, error) {
+	res := s.db.WithContext(ctx).Where("time < ?", before.UTC()).Delete(&Event{})
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"crypto/x509"
+	"net/http"
+	"production-go-api-template/pkg/constants"
+)
+
+const (
+	certPrefixCN    = "CN="
+	certPrefixDNS   = "DNS:"
+	certPrefixURI   = "URI:"
+	certPrefixEmail = "EMAIL:"
+	certPrefixIP    = "IP:"
+)
+
+// CertificateIdentities lists the names a client certificate can be mapped
+// by: the full subject, the common name and every SAN, each in the form
+// used by the "certificates" field of the clients file.
+func CertificateIdentities(cert *x509.Certificate) []string {
+	identities := []string{cert.Subject.String()}
+	if cert.Subject.CommonName != constants.EmptyString {
+		identities = append(identities, certPrefixCN+cert.Subject.CommonName)
+	}
+	for _, name := range cert.DNSNames {
+		identities = append(identities, certPrefixDNS+name)
+	}
+	for _, uri := range cert.URIs {
+		identities = append(identities, certPrefixURI+uri.String())
+	}
+	for _, email := range cert.EmailAddresses {
+		identities = append(identities, certPrefixEmail+email)
+	}
+	for _, ip := range cert.IPAddresses {
+		identities = append(identities, certPrefixIP+ip.String())
+	}
+	return identities
+}
+
+// VerifiedClientCertificate returns the leaf certificate of a TLS connection
+// only when it was verified against the configured client CA bundle.
+func VerifiedClientCertificate(r *http.Request) *x509.Certificate {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == constants.ZeroIndex {
+		return nil
+	}
+	return r.TLS.VerifiedChains[constants.ZeroIndex][constants.ZeroIndex]
+}
+
+func (r *Registry) LookupCertificate(cert *x509.Certificate) (Client, bool) {
+	for _, identity := range CertificateIdentities(cert) {
+		if idx, ok := r.byCert[identity]; ok {
+			return r.clients[idx], true
+		}
+	}
+	return Client{}, false
+}
@@ -0,0 +1,244 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"production-go-api-template/config"
+	"production-go-api-template/pkg/constants"
+	"time"
+)
+
+const (
+	legacyClientID = "default"
+)
+
+var (
+	ErrUnknownKey     = errors.New("unknown key id")
+	ErrKeyExpired     = errors.New("key expired")
+	ErrKeyNotYetValid = errors.New("key not yet valid")
+)
+
+type Client struct {
+	ID                 string   `json:"id"`
+	Token              string   `json:"token"`
+	Secret             string   `json:"secret"`
+	Keys               []Key    `json:"keys,omitempty"`
+	Enabled            bool     `json:"enabled"`
+	Admin              bool     `json:"admin,omitempty"`
+	Scopes             []string `json:"scopes,omitempty"`
+	Certificates       []string `json:"certificates,omitempty"`
+	RequireCertificate bool     `json:"require_certificate,omitempty"`
+	RateLimits         []string `json:"rate_limits,omitempty"`
+}
+
+type Key struct {
+	ID        string    `json:"id"`
+	Secret    string    `json:"secret"`
+	NotBefore time.Time `json:"not_before,omitempty"`
+	NotAfter  time.Time `json:"not_after,omitempty"`
+}
+
+func (k Key) check(now time.Time) error {
+	if !k.NotBefore.IsZero() && now.Before(k.NotBefore) {
+		return ErrKeyNotYetValid
+	}
+	if !k.NotAfter.IsZero() && !now.Before(k.NotAfter) {
+		return ErrKeyExpired
+	}
+	return nil
+}
+
+// SigningSecrets returns the secrets a signature may be verified against.
+// With a key ID only that key is considered; without one, the client secret
+// and every key that is currently inside its validity window are returned.
+func (c Client) SigningSecrets(keyID string, now time.Time) ([]string, error) {
+	if keyID != constants.EmptyString {
+		for _, k := range c.Keys {
+			if k.ID != keyID {
+				continue
+			}
+			if err := k.check(now); err != nil {
+				return nil, err
+			}
+			return []string{k.Secret}, nil
+		}
+		return nil, ErrUnknownKey
+	}
+
+	var secrets []string
+	if c.Secret != constants.EmptyString {
+		secrets = append(secrets, c.Secret)
+	}
+	for _, k := range c.Keys {
+		if k.check(now) == nil {
+			secrets = append(secrets, k.Secret)
+		}
+	}
+	return secrets, nil
+}
+
+func (c Client) validate() error {
+	if c.ID == constants.EmptyString {
+		return errors.New("client id is required")
+	}
+	if len(c.Certificates) == constants.ZeroIndex {
+		if c.Token == constants.EmptyString {
+			return fmt.Errorf("client %q: token or certificates are required", c.ID)
+		}
+		if c.RequireCertificate {
+			return fmt.Errorf("client %q: require_certificate needs certificates", c.ID)
+		}
+	}
+	if c.Token != constants.EmptyString && c.Secret == constants.EmptyString && len(c.Keys) == constants.ZeroIndex {
+		return fmt.Errorf("client %q: secret or keys are required", c.ID)
+	}
+
+	keyIDs := make(map[string]struct{}, len(c.Keys))
+	for _, k := range c.Keys {
+		if k.ID == constants.EmptyString || k.Secret == constants.EmptyString {
+			return fmt.Errorf("client %q: key id and secret are required", c.ID)
+		}
+		if _, dup := keyIDs[k.ID]; dup {
+			return fmt.Errorf("client %q: duplicate key id %q", c.ID, k.ID)
+		}
+		if !k.NotBefore.IsZero() && !k.NotAfter.IsZero() && !k.NotBefore.Before(k.NotAfter) {
+			return fmt.Errorf("client %q: key %q has an empty validity window", c.ID, k.ID)
+		}
+		keyIDs[k.ID] = struct{}{}
+	}
+	return nil
+}
+
+type Registry struct {
+	clients []Client
+	byID    map[string]int
+	byCert  map[string]int
+}
+
+func NewRegistry(clients []Client) (*Registry, error) {
+	reg := &Registry{
+		clients: make([]Client, 0, len(clients)),
+		byID:    make(map[string]int, len(clients)),
+		byCert:  make(map[string]int),
+	}
+
+	tokens := make(map[string]struct{}, len(clients))
+	for _, c := range clients {
+		if err := c.validate(); err != nil {
+			return nil, err
+		}
+		if _, dup := reg.byID[c.ID]; dup {
+			return nil, fmt.Errorf("duplicate client id %q", c.ID)
+		}
+		if _, dup := tokens[c.Token]; dup && c.Token != constants.EmptyString {
+			return nil, fmt.Errorf("client %q: token already assigned to another client", c.ID)
+		}
+		for _, identity := range c.Certificates {
+			if _, dup := reg.byCert[identity]; dup {
+				return nil, fmt.Errorf("client %q: certificate %q already assigned to another client", c.ID, identity)
+			}
+			reg.byCert[identity] = len(reg.clients)
+		}
+		tokens[c.Token] = struct{}{}
+		reg.byID[c.ID] = len(reg.clients)
+		reg.clients = append(reg.clients, c)
+	}
+
+	if len(reg.clients) == constants.ZeroIndex {
+		return nil, errors.New("no API clients configured")
+	}
+
+	return reg, nil
+}
+
+func NewRegistryFromConfig(cfg config.ConfAuth) (*Registry, error) {
+	var clients []Client
+
+	if cfg.ClientsFile != constants.EmptyString {
+		fileClients, err := LoadClientsFile(cfg.ClientsFile)
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, fileClients...)
+	}
+
+	if cfg.APITokens != constants.EmptyString || cfg.HMACSecrets != constants.EmptyString {
+		clients = append(clients, Client{
+			ID:      legacyClientID,
+			Token:   cfg.APITokens,
+			Secret:  cfg.HMACSecrets,
+			Enabled: true,
+		})
+	}
+
+	return NewRegistry(clients)
+}
+
+func LoadClientsFile(path string) ([]Client, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read clients file: %w", err)
+	}
+
+	var clients []Client
+	if err := json.Unmarshal(data, &clients); err != nil {
+		return nil, fmt.Errorf("failed to parse clients file: %w", err)
+	}
+	return clients, nil
+}
+
+func (r *Registry) Lookup(token string) (Client, bool) {
+	var (
+		found Client
+		ok    bool
+	)
+	for _, c := range r.clients {
+		if c.Token == constants.EmptyString {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(c.Token), []byte(token)) == 1 {
+			found, ok = c, true
+		}
+	}
+	return found, ok
+}
+
+// Authenticate checks client credentials as sent to the token endpoint. The
+// secret must match the client secret or one of its currently valid keys.
+func (r *Registry) Authenticate(id, secret string, now time.Time) (Client, bool) {
+	c, ok := r.Get(id)
+	if !ok || !c.Enabled {
+		return Client{}, false
+	}
+
+	secrets, err := c.SigningSecrets(constants.EmptyString, now)
+	if err != nil {
+		return Client{}, false
+	}
+
+	matched := false
+	for _, s := range secrets {
+		if subtle.ConstantTimeCompare([]byte(s), []byte(secret)) == 1 {
+			matched = true
+		}
+	}
+	if !matched {
+		return Client{}, false
+	}
+	return c, true
+}
+
+func (r *Registry) Get(id string) (Client, bool) {
+	idx, ok := r.byID[id]
+	if !ok {
+		return Client{}, false
+	}
+	return r.clients[idx], true
+}
+
+func (r *Registry) Clients() []Client {
+	return append([]Client(nil), r.clients...)
+}
@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"production-go-api-template/pkg/constants"
+)
+
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+
+	ktyOct = "oct"
+	ktyRSA = "RSA"
+	ktyOKP = "OKP"
+
+	crvEd25519 = "Ed25519"
+)
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	K   string `json:"k"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+}
+
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+// VerificationKey is a public or shared key bound to exactly one algorithm,
+// so a token can never pick a different algorithm than the key was made for.
+type VerificationKey struct {
+	ID  string
+	Alg string
+	key any
+}
+
+func NewHMACKey(id, secret string) VerificationKey {
+	return VerificationKey{ID: id, Alg: AlgHS256, key: []byte(secret)}
+}
+
+func LoadJWKSFile(path string) ([]VerificationKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS file: %w", err)
+	}
+
+	var set jwkSet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS file: %w", err)
+	}
+
+	keys := make([]VerificationKey, 0, len(set.Keys))
+	for _, k := range set.Keys {
+		vk, err := k.verificationKey()
+		if err != nil {
+			return nil, fmt.Errorf("JWKS key %q: %w", k.Kid, err)
+		}
+		keys = append(keys, vk)
+	}
+	return keys, nil
+}
+
+func (k jwk) verificationKey() (VerificationKey, error) {
+	var vk VerificationKey
+	vk.ID = k.Kid
+
+	switch k.Kty {
+	case ktyOct:
+		secret, err := decodeSegment(k.K)
+		if err != nil || len(secret) == constants.ZeroIndex {
+			return vk, errors.New("invalid symmetric key")
+		}
+		vk.Alg, vk.key = AlgHS256, secret
+	case ktyRSA:
+		n, err := decodeSegment(k.N)
+		if err != nil {
+			return vk, errors.New("invalid RSA modulus")
+		}
+		e, err := decodeSegment(k.E)
+		if err != nil || len(e) == constants.ZeroIndex {
+			return vk, errors.New("invalid RSA exponent")
+		}
+		vk.Alg = AlgRS256
+		vk.key = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	case ktyOKP:
+		if k.Crv != crvEd25519 {
+			return vk, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeSegment(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return vk, errors.New("invalid Ed25519 public key")
+		}
+		vk.Alg, vk.key = AlgEdDSA, ed25519.PublicKey(x)
+	default:
+		return vk, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+
+	if k.Alg != constants.EmptyString && k.Alg != vk.Alg {
+		return vk, fmt.Errorf("algorithm %q does not match key type %q", k.Alg, k.Kty)
+	}
+	return vk, nil
+}
+
+func decodeSegment(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
+
+func encodeSegment(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
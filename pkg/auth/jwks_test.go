@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeJWKS(t *testing.T, keys ...map[string]any) string {
+	t.Helper()
+	data, err := json.Marshal(map[string]any{"keys": keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func rsaJWK(kid string, pub *rsa.PublicKey) map[string]any {
+	return map[string]any{
+		"kty": ktyRSA,
+		"kid": kid,
+		"n":   encodeSegment(pub.N.Bytes()),
+		"e":   encodeSegment(big.NewInt(int64(pub.E)).Bytes()),
+	}
+}
+
+func TestLoadJWKSFileVerifiesTokens(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := writeJWKS(t,
+		rsaJWK("rs", &rsaKey.PublicKey),
+		map[string]any{"kty": ktyOKP, "kid": "ed", "crv": crvEd25519, "x": encodeSegment(edPub), "alg": AlgEdDSA},
+		map[string]any{"kty": ktyOct, "kid": "hs", "k": encodeSegment([]byte(testHMACSecret))},
+	)
+	keys, err := LoadJWKSFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantAlgs := map[string]string{"rs": AlgRS256, "ed": AlgEdDSA, "hs": AlgHS256}
+	if len(keys) != len(wantAlgs) {
+		t.Fatalf("loaded %d keys, want %d", len(keys), len(wantAlgs))
+	}
+	for _, k := range keys {
+		if k.Alg != wantAlgs[k.ID] {
+			t.Errorf("key %q has alg %q, want %q", k.ID, k.Alg, wantAlgs[k.ID])
+		}
+	}
+
+	now := time.Unix(1700000000, 0)
+	claims := map[string]any{"sub": "alice", "exp": now.Add(time.Minute).Unix()}
+	verifier := NewJWTVerifier(keys, "", "", 0)
+	for _, tt := range []struct {
+		alg, kid string
+		secret   any
+	}{
+		{AlgRS256, "rs", rsaKey},
+		{AlgEdDSA, "ed", edKey},
+		{AlgHS256, "hs", []byte(testHMACSecret)},
+	} {
+		token := signToken(t, map[string]any{"alg": tt.alg, "kid": tt.kid}, claims, tt.secret)
+		if _, err := verifier.Verify(token, now); err != nil {
+			t.Errorf("%s token: %v", tt.alg, err)
+		}
+	}
+
+	// The RSA modulus is public; it must not verify as an HMAC secret.
+	forged := signToken(t, map[string]any{"alg": AlgHS256, "kid": "rs"}, claims, rsaKey.PublicKey.N.Bytes())
+	if _, err := verifier.Verify(forged, now); !errors.Is(err, ErrUnknownSigningKey) {
+		t.Errorf("HS256 token with RSA kid: error = %v, want %v", err, ErrUnknownSigningKey)
+	}
+}
+
+func TestLoadJWKSFileRejectsInvalidKeys(t *testing.T) {
+	edPub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		key  map[string]any
+		want string
+	}{
+		{"alg does not match kty", map[string]any{"kty": ktyOct, "kid": "k", "k": encodeSegment([]byte("s")), "alg": AlgRS256}, "does not match"},
+		{"RSA key declared as HS256", map[string]any{"kty": ktyRSA, "kid": "k", "n": encodeSegment([]byte{1}), "e": "AQAB", "alg": AlgHS256}, "does not match"},
+		{"alg none", map[string]any{"kty": ktyOct, "kid": "k", "k": encodeSegment([]byte("s")), "alg": "none"}, "does not match"},
+		{"empty symmetric key", map[string]any{"kty": ktyOct, "kid": "k", "k": ""}, "invalid symmetric key"},
+		{"RSA without exponent", map[string]any{"kty": ktyRSA, "kid": "k", "n": encodeSegment([]byte{1})}, "invalid RSA exponent"},
+		{"short Ed25519 key", map[string]any{"kty": ktyOKP, "kid": "k", "crv": crvEd25519, "x": encodeSegment(edPub[:16])}, "invalid Ed25519"},
+		{"unsupported curve", map[string]any{"kty": ktyOKP, "kid": "k", "crv": "X25519", "x": encodeSegment(edPub)}, "unsupported curve"},
+		{"unsupported kty", map[string]any{"kty": "EC", "kid": "k"}, "unsupported key type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadJWKSFile(writeJWKS(t, tt.key))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("LoadJWKSFile() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadJWKSFileErrors(t *testing.T) {
+	if _, err := LoadJWKSFile(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("missing file: expected an error")
+	}
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadJWKSFile(path); err == nil {
+		t.Error("invalid JSON: expected an error")
+	}
+}
@@ -0,0 +1,298 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"production-go-api-template/config"
+	"production-go-api-template/pkg/constants"
+	"production-go-api-template/pkg/contextkeys"
+	"strings"
+	"time"
+)
+
+const (
+	jwtSegments  = 3
+	jwtSeparator = "."
+)
+
+var (
+	ErrMalformedToken    = errors.New("malformed token")
+	ErrUnknownSigningKey = errors.New("unknown signing key")
+	ErrInvalidTokenSig   = errors.New("invalid token signature")
+	ErrTokenExpired      = errors.New("token expired")
+	ErrTokenNotYetValid  = errors.New("token not yet valid")
+	ErrInvalidIssuer     = errors.New("invalid token issuer")
+	ErrInvalidAudience   = errors.New("invalid token audience")
+)
+
+type Claims struct {
+	ID        string
+	Subject   string
+	Issuer    string
+	Audience  []string
+	ExpiresAt time.Time
+	NotBefore time.Time
+	IssuedAt  time.Time
+	Scopes    []string
+	Extra     map[string]any
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid,omitempty"`
+	Typ string `json:"typ,omitempty"`
+}
+
+type JWTVerifier struct {
+	keys     []VerificationKey
+	issuer   string
+	audience string
+	leeway   time.Duration
+}
+
+func NewJWTVerifier(keys []VerificationKey, issuer, audience string, leeway time.Duration) *JWTVerifier {
+	return &JWTVerifier{keys: keys, issuer: issuer, audience: audience, leeway: leeway}
+}
+
+// NewJWTVerifierFromConfig returns nil when no JWT keys are configured.
+func NewJWTVerifierFromConfig(cfg config.ConfAuth) (*JWTVerifier, error) {
+	var keys []VerificationKey
+
+	if cfg.JWTJWKSFile != constants.EmptyString {
+		fileKeys, err := LoadJWKSFile(cfg.JWTJWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, fileKeys...)
+	}
+	if cfg.JWTHS256Secret != constants.EmptyString {
+		keys = append(keys, NewHMACKey(constants.EmptyString, cfg.JWTHS256Secret))
+	}
+
+	if len(keys) == constants.ZeroIndex {
+		return nil, nil
+	}
+	return NewJWTVerifier(keys, cfg.JWTIssuer, cfg.JWTAudience, cfg.JWTLeeway), nil
+}
+
+func ClaimsFromContext(ctx context.Context) (Claims, bool) {
+	claims, ok := ctx.Value(contextkeys.CtxKeyClaims).(Claims)
+	return claims, ok
+}
+
+// LooksLikeJWT tells compact JWTs apart from static bearer tokens, which
+// never contain a dot.
+func LooksLikeJWT(token string) bool {
+	return strings.Count(token, jwtSeparator) == jwtSegments-constants.FirstIndex
+}
+
+func (v *JWTVerifier) Verify(token string, now time.Time) (Claims, error) {
+	parts := strings.Split(token, jwtSeparator)
+	if len(parts) != jwtSegments {
+		return Claims{}, ErrMalformedToken
+	}
+
+	var header jwtHeader
+	if err := decodeJSONSegment(parts[0], &header); err != nil {
+		return Claims{}, ErrMalformedToken
+	}
+
+	key, ok := v.key(header)
+	if !ok {
+		return Claims{}, ErrUnknownSigningKey
+	}
+
+	sig, err := decodeSegment(parts[2])
+	if err != nil {
+		return Claims{}, ErrMalformedToken
+	}
+	if !verifySignature(key, []byte(parts[0]+jwtSeparator+parts[1]), sig) {
+		return Claims{}, ErrInvalidTokenSig
+	}
+
+	var raw map[string]any
+	if err := decodeJSONSegment(parts[1], &raw); err != nil {
+		return Claims{}, ErrMalformedToken
+	}
+	claims, err := parseClaims(raw)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	return claims, v.validate(claims, now)
+}
+
+func (v *JWTVerifier) key(header jwtHeader) (VerificationKey, bool) {
+	var candidates []VerificationKey
+	for _, k := range v.keys {
+		if k.Alg != header.Alg {
+			continue
+		}
+		if header.Kid != constants.EmptyString && k.ID != header.Kid {
+			continue
+		}
+		candidates = append(candidates, k)
+	}
+	if len(candidates) != constants.FirstIndex {
+		return VerificationKey{}, false
+	}
+	return candidates[0], true
+}
+
+func (v *JWTVerifier) validate(c Claims, now time.Time) error {
+	if c.ExpiresAt.IsZero() || !now.Before(c.ExpiresAt.Add(v.leeway)) {
+		return ErrTokenExpired
+	}
+	if !c.NotBefore.IsZero() && now.Add(v.leeway).Before(c.NotBefore) {
+		return ErrTokenNotYetValid
+	}
+	if v.issuer != constants.EmptyString && c.Issuer != v.issuer {
+		return ErrInvalidIssuer
+	}
+	if v.audience != constants.EmptyString {
+		for _, aud := range c.Audience {
+			if aud == v.audience {
+				return nil
+			}
+		}
+		return ErrInvalidAudience
+	}
+	return nil
+}
+
+func verifySignature(k VerificationKey, signingInput, sig []byte) bool {
+	switch k.Alg {
+	case AlgHS256:
+		secret, ok := k.key.([]byte)
+		if !ok {
+			return false
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(signingInput)
+		return hmac.Equal(mac.Sum(nil), sig)
+	case AlgRS256:
+		pub, ok := k.key.(*rsa.PublicKey)
+		if !ok {
+			return false
+		}
+		digest := sha256.Sum256(signingInput)
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig) == nil
+	case AlgEdDSA:
+		pub, ok := k.key.(ed25519.PublicKey)
+		if !ok {
+			return false
+		}
+		return ed25519.Verify(pub, signingInput, sig)
+	default:
+		return false
+	}
+}
+
+func parseClaims(raw map[string]any) (Claims, error) {
+	c := Claims{Extra: raw}
+
+	var ok bool
+	if v, present := raw["sub"]; present {
+		if c.Subject, ok = v.(string); !ok {
+			return Claims{}, ErrMalformedToken
+		}
+	}
+	if v, present := raw["iss"]; present {
+		if c.Issuer, ok = v.(string); !ok {
+			return Claims{}, ErrMalformedToken
+		}
+	}
+
+	if v, present := raw["jti"]; present {
+		if c.ID, ok = v.(string); !ok {
+			return Claims{}, ErrMalformedToken
+		}
+	}
+	if v, present := raw["scope"]; present {
+		scope, isString := v.(string)
+		if !isString {
+			return Claims{}, ErrMalformedToken
+		}
+		c.Scopes = strings.Fields(scope)
+	}
+
+	switch aud := raw["aud"].(type) {
+	case nil:
+	case string:
+		c.Audience = []string{aud}
+	case []any:
+		for _, a := range aud {
+			s, isString := a.(string)
+			if !isString {
+				return Claims{}, ErrMalformedToken
+			}
+			c.Audience = append(c.Audience, s)
+		}
+	default:
+		return Claims{}, ErrMalformedToken
+	}
+
+	var err error
+	if c.ExpiresAt, err = numericDate(raw, "exp"); err != nil {
+		return Claims{}, err
+	}
+	if c.NotBefore, err = numericDate(raw, "nbf"); err != nil {
+		return Claims{}, err
+	}
+	if c.IssuedAt, err = numericDate(raw, "iat"); err != nil {
+		return Claims{}, err
+	}
+	return c, nil
+}
+
+func numericDate(raw map[string]any, name string) (time.Time, error) {
+	v, present := raw[name]
+	if !present {
+		return time.Time{}, nil
+	}
+	n, ok := v.(json.Number)
+	if !ok {
+		return time.Time{}, ErrMalformedToken
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return time.Time{}, ErrMalformedToken
+	}
+	return time.Unix(int64(f), constants.ZeroIndex), nil
+}
+
+// SignHS256 produces a compact JWT over the given claims. It is used for
+// tokens the server issues itself; foreign tokens are only ever verified.
+func SignHS256(claims map[string]any, kid, secret string) (string, error) {
+	header := jwtHeader{Alg: AlgHS256, Kid: kid, Typ: "JWT"}
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return constants.EmptyString, err
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return constants.EmptyString, err
+	}
+
+	signingInput := encodeSegment(headerJSON) + jwtSeparator + encodeSegment(claimsJSON)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signingInput))
+	return signingInput + jwtSeparator + encodeSegment(mac.Sum(nil)), nil
+}
+
+func decodeJSONSegment(segment string, v any) error {
+	data, err := decodeSegment(segment)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
@@ -0,0 +1,311 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testHMACSecret = "test-hmac-secret-test-hmac-secret"
+
+type testKeys struct {
+	rsa     *rsa.PrivateKey
+	ed25519 ed25519.PrivateKey
+}
+
+func newTestKeys(t *testing.T) testKeys {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testKeys{rsa: rsaKey, ed25519: edKey}
+}
+
+// signToken builds a compact JWT with an arbitrary header, so tests can forge
+// tokens the verifier must reject. secret is an HMAC secret, an RSA private
+// key or an Ed25519 private key; nil leaves the signature empty.
+func signToken(t *testing.T, header map[string]any, claims map[string]any, secret any) string {
+	t.Helper()
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := encodeSegment(headerJSON) + jwtSeparator + encodeSegment(claimsJSON)
+
+	var sig []byte
+	switch k := secret.(type) {
+	case nil:
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(input))
+		sig = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256([]byte(input))
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	case ed25519.PrivateKey:
+		sig = ed25519.Sign(k, []byte(input))
+	default:
+		t.Fatalf("unsupported signing key %T", secret)
+	}
+	return input + jwtSeparator + encodeSegment(sig)
+}
+
+func TestJWTVerifierAlgorithms(t *testing.T) {
+	keys := newTestKeys(t)
+	now := time.Unix(1700000000, 0)
+	claims := map[string]any{"sub": "alice", "exp": now.Add(time.Minute).Unix()}
+
+	verifier := NewJWTVerifier([]VerificationKey{
+		NewHMACKey("hs", testHMACSecret),
+		{ID: "rs", Alg: AlgRS256, key: &keys.rsa.PublicKey},
+		{ID: "ed", Alg: AlgEdDSA, key: keys.ed25519.Public()},
+	}, "", "", 0)
+
+	rsaPublicDER := keys.rsa.PublicKey.N.Bytes()
+
+	tests := []struct {
+		name   string
+		header map[string]any
+		secret any
+		want   error
+	}{
+		{"HS256", map[string]any{"alg": AlgHS256, "kid": "hs"}, []byte(testHMACSecret), nil},
+		{"RS256", map[string]any{"alg": AlgRS256, "kid": "rs"}, keys.rsa, nil},
+		{"EdDSA", map[string]any{"alg": AlgEdDSA, "kid": "ed"}, keys.ed25519, nil},
+		{"HS256 without kid", map[string]any{"alg": AlgHS256}, []byte(testHMACSecret), nil},
+		{"wrong HMAC secret", map[string]any{"alg": AlgHS256, "kid": "hs"}, []byte("other"), ErrInvalidTokenSig},
+		{"alg none", map[string]any{"alg": "none"}, nil, ErrUnknownSigningKey},
+		{"alg none with kid", map[string]any{"alg": "none", "kid": "hs"}, nil, ErrUnknownSigningKey},
+		{"empty signature", map[string]any{"alg": AlgHS256, "kid": "hs"}, nil, ErrInvalidTokenSig},
+		{"HS256 with RSA kid", map[string]any{"alg": AlgHS256, "kid": "rs"}, rsaPublicDER, ErrUnknownSigningKey},
+		{"RS256 with HMAC kid", map[string]any{"alg": AlgRS256, "kid": "hs"}, keys.rsa, ErrUnknownSigningKey},
+		{"EdDSA with RSA kid", map[string]any{"alg": AlgEdDSA, "kid": "rs"}, keys.ed25519, ErrUnknownSigningKey},
+		{"unknown kid", map[string]any{"alg": AlgRS256, "kid": "other"}, keys.rsa, ErrUnknownSigningKey},
+		{"unsupported alg", map[string]any{"alg": "HS512", "kid": "hs"}, []byte(testHMACSecret), ErrUnknownSigningKey},
+		{"lowercase alg", map[string]any{"alg": "hs256", "kid": "hs"}, []byte(testHMACSecret), ErrUnknownSigningKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := signToken(t, tt.header, claims, tt.secret)
+			got, err := verifier.Verify(token, now)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.want)
+			}
+			if tt.want == nil && got.Subject != "alice" {
+				t.Errorf("Subject = %q, want alice", got.Subject)
+			}
+		})
+	}
+}
+
+// An attacker who knows an RSA public key must not be able to use it as an
+// HMAC secret, even when the verifier also holds HMAC keys.
+func TestJWTVerifierAlgorithmConfusion(t *testing.T) {
+	keys := newTestKeys(t)
+	now := time.Unix(1700000000, 0)
+	claims := map[string]any{"sub": "mallory", "exp": now.Add(time.Minute).Unix()}
+
+	verifier := NewJWTVerifier([]VerificationKey{
+		{ID: "rs", Alg: AlgRS256, key: &keys.rsa.PublicKey},
+	}, "", "", 0)
+
+	for _, secret := range [][]byte{keys.rsa.PublicKey.N.Bytes(), []byte(testHMACSecret)} {
+		token := signToken(t, map[string]any{"alg": AlgHS256, "kid": "rs"}, claims, secret)
+		if _, err := verifier.Verify(token, now); !errors.Is(err, ErrUnknownSigningKey) {
+			t.Errorf("HS256 token verified against an RSA key: error = %v", err)
+		}
+	}
+}
+
+func TestJWTVerifierAmbiguousKeys(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	claims := map[string]any{"exp": now.Add(time.Minute).Unix()}
+	verifier := NewJWTVerifier([]VerificationKey{
+		NewHMACKey("a", testHMACSecret),
+		NewHMACKey("b", "another-secret"),
+	}, "", "", 0)
+
+	token := signToken(t, map[string]any{"alg": AlgHS256}, claims, []byte(testHMACSecret))
+	if _, err := verifier.Verify(token, now); !errors.Is(err, ErrUnknownSigningKey) {
+		t.Fatalf("token without kid and two candidate keys: error = %v, want %v", err, ErrUnknownSigningKey)
+	}
+
+	token = signToken(t, map[string]any{"alg": AlgHS256, "kid": "a"}, claims, []byte(testHMACSecret))
+	if _, err := verifier.Verify(token, now); err != nil {
+		t.Fatalf("token with kid: error = %v", err)
+	}
+}
+
+func TestJWTVerifierClaims(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	const leeway = 30 * time.Second
+	verifier := NewJWTVerifier([]VerificationKey{NewHMACKey("", testHMACSecret)}, "https://issuer", "api", leeway)
+
+	valid := func(overrides map[string]any) map[string]any {
+		claims := map[string]any{
+			"sub": "alice",
+			"iss": "https://issuer",
+			"aud": "api",
+			"exp": now.Add(time.Minute).Unix(),
+		}
+		for k, v := range overrides {
+			if v == nil {
+				delete(claims, k)
+				continue
+			}
+			claims[k] = v
+		}
+		return claims
+	}
+
+	tests := []struct {
+		name   string
+		claims map[string]any
+		want   error
+	}{
+		{"valid", valid(nil), nil},
+		{"missing exp", valid(map[string]any{"exp": nil}), ErrTokenExpired},
+		{"expired", valid(map[string]any{"exp": now.Add(-time.Minute).Unix()}), ErrTokenExpired},
+		{"expired at now minus leeway", valid(map[string]any{"exp": now.Add(-leeway).Unix()}), ErrTokenExpired},
+		{"expired within leeway", valid(map[string]any{"exp": now.Add(-leeway / 2).Unix()}), nil},
+		{"not yet valid", valid(map[string]any{"nbf": now.Add(time.Minute).Unix()}), ErrTokenNotYetValid},
+		{"nbf within leeway", valid(map[string]any{"nbf": now.Add(leeway / 2).Unix()}), nil},
+		{"nbf in the past", valid(map[string]any{"nbf": now.Add(-time.Minute).Unix()}), nil},
+		{"fractional exp", valid(map[string]any{"exp": float64(now.Add(time.Minute).Unix()) + 0.5}), nil},
+		{"string exp", valid(map[string]any{"exp": "tomorrow"}), ErrMalformedToken},
+		{"issuer mismatch", valid(map[string]any{"iss": "https://other"}), ErrInvalidIssuer},
+		{"missing issuer", valid(map[string]any{"iss": nil}), ErrInvalidIssuer},
+		{"issuer not a string", valid(map[string]any{"iss": 1}), ErrMalformedToken},
+		{"audience mismatch", valid(map[string]any{"aud": "other"}), ErrInvalidAudience},
+		{"missing audience", valid(map[string]any{"aud": nil}), ErrInvalidAudience},
+		{"audience list", valid(map[string]any{"aud": []string{"other", "api"}}), nil},
+		{"audience list mismatch", valid(map[string]any{"aud": []string{"other"}}), ErrInvalidAudience},
+		{"audience list with number", valid(map[string]any{"aud": []any{"api", 1}}), ErrMalformedToken},
+		{"audience object", valid(map[string]any{"aud": map[string]any{"api": true}}), ErrMalformedToken},
+		{"subject not a string", valid(map[string]any{"sub": 42}), ErrMalformedToken},
+		{"scope not a string", valid(map[string]any{"scope": []string{"items:read"}}), ErrMalformedToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := signToken(t, map[string]any{"alg": AlgHS256}, tt.claims, []byte(testHMACSecret))
+			if _, err := verifier.Verify(token, now); !errors.Is(err, tt.want) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestJWTVerifierParsesClaims(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	verifier := NewJWTVerifier([]VerificationKey{NewHMACKey("", testHMACSecret)}, "", "", 0)
+
+	token := signToken(t, map[string]any{"alg": AlgHS256}, map[string]any{
+		"sub":   "alice",
+		"jti":   "t-1",
+		"aud":   []string{"a", "b"},
+		"scope": "items:read  items:write",
+		"exp":   now.Add(time.Minute).Unix(),
+		"iat":   now.Unix(),
+		"tier":  "gold",
+	}, []byte(testHMACSecret))
+
+	claims, err := verifier.Verify(token, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.ID != "t-1" || claims.Subject != "alice" {
+		t.Errorf("ID, Subject = %q, %q", claims.ID, claims.Subject)
+	}
+	if strings.Join(claims.Audience, ",") != "a,b" {
+		t.Errorf("Audience = %v", claims.Audience)
+	}
+	if strings.Join(claims.Scopes, ",") != "items:read,items:write" {
+		t.Errorf("Scopes = %v", claims.Scopes)
+	}
+	if !claims.IssuedAt.Equal(now) || !claims.ExpiresAt.Equal(now.Add(time.Minute)) {
+		t.Errorf("IssuedAt, ExpiresAt = %v, %v", claims.IssuedAt, claims.ExpiresAt)
+	}
+	if claims.Extra["tier"] != "gold" {
+		t.Errorf("Extra = %v", claims.Extra)
+	}
+}
+
+func TestJWTVerifierMalformed(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	verifier := NewJWTVerifier([]VerificationKey{NewHMACKey("", testHMACSecret)}, "", "", 0)
+	valid := signToken(t, map[string]any{"alg": AlgHS256}, map[string]any{"exp": now.Add(time.Minute).Unix()}, []byte(testHMACSecret))
+	parts := strings.Split(valid, jwtSeparator)
+
+	tampered := signToken(t, map[string]any{"alg": AlgHS256}, map[string]any{"exp": now.Add(time.Hour).Unix()}, nil)
+	tamperedParts := strings.Split(tampered, jwtSeparator)
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"two segments", parts[0] + "." + parts[1], ErrMalformedToken},
+		{"four segments", valid + ".x", ErrMalformedToken},
+		{"header not base64", "!!." + parts[1] + "." + parts[2], ErrMalformedToken},
+		{"header not JSON", encodeSegment([]byte("alg")) + "." + parts[1] + "." + parts[2], ErrMalformedToken},
+		{"padded signature", valid + "=", ErrMalformedToken},
+		{"payload swapped", parts[0] + "." + tamperedParts[1] + "." + parts[2], ErrInvalidTokenSig},
+		{"payload not JSON", signSegments(t, parts[0], encodeSegment([]byte("[1,2"))), ErrMalformedToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := verifier.Verify(tt.token, now); !errors.Is(err, tt.want) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func signSegments(t *testing.T, header, payload string) string {
+	t.Helper()
+	mac := hmac.New(sha256.New, []byte(testHMACSecret))
+	mac.Write([]byte(header + jwtSeparator + payload))
+	return header + jwtSeparator + payload + jwtSeparator + encodeSegment(mac.Sum(nil))
+}
+
+func TestSignHS256RoundTrip(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	token, err := SignHS256(map[string]any{"sub": "client", "exp": now.Add(time.Minute).Unix()}, "oauth", testHMACSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifier := NewJWTVerifier([]VerificationKey{NewHMACKey("oauth", testHMACSecret)}, "", "", 0)
+	claims, err := verifier.Verify(token, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "client" {
+		t.Errorf("Subject = %q, want client", claims.Subject)
+	}
+	if !LooksLikeJWT(token) || LooksLikeJWT("static-bearer-token") {
+		t.Error("LooksLikeJWT does not tell JWTs from static tokens")
+	}
+}
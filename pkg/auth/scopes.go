@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"context"
+	"production-go-api-template/pkg/contextkeys"
+	"slices"
+)
+
+// ScopeAll grants every scope. Admin clients and clients without a scopes
+// list hold it so deployments that predate scopes keep full access.
+const ScopeAll = "*"
+
+// ScopeAdmin is required, together with the admin flag of the client, on the
+// admin API, so a down-scoped token of an admin client cannot use it.
+const ScopeAdmin = "admin"
+
+func (c Client) GrantedScopes() []string {
+	if c.Admin || len(c.Scopes) == 0 {
+		return []string{ScopeAll}
+	}
+	return c.Scopes
+}
+
+// MissingScopes returns the required scopes that are not covered by granted.
+func MissingScopes(granted, required []string) []string {
+	if slices.Contains(granted, ScopeAll) {
+		return nil
+	}
+
+	var missing []string
+	for _, scope := range required {
+		if !slices.Contains(granted, scope) {
+			missing = append(missing, scope)
+		}
+	}
+	return missing
+}
+
+func ScopesFromContext(ctx context.Context) []string {
+	scopes, _ := ctx.Value(contextkeys.CtxKeyScopes).([]string)
+	return scopes
+}
@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"production-go-api-template/pkg/constants"
+	"sort"
+	"strings"
+)
+
+const (
+	HeaderAuthorization    = "Authorization"
+	HeaderTimestamp        = "X-Timestamp"
+	HeaderSignature        = "X-Signature"
+	HeaderSignatureVersion = "X-Signature-Version"
+	HeaderKeyID            = "X-Key-Id"
+	HeaderContentSHA256    = "X-Content-SHA256"
+	HeaderNonce            = "X-Nonce"
+	HeaderServerTime       = "X-Server-Time"
+
+	BearerPrefix = "Bearer "
+
+	SignatureV1 = "v1"
+	SignatureV2 = "v2"
+
+	querySeparator = "&"
+	queryAssign    = "="
+)
+
+var ErrUnsupportedVersion = errors.New("unsupported signature version")
+
+// CanonicalRequest holds every request attribute that can be covered by a
+// signature. v1 signs token|timestamp|method|path; v2 additionally signs the
+// sorted query string and the hex SHA-256 of the request body. A nonce, when
+// present, is appended as the last segment in both versions.
+type CanonicalRequest struct {
+	Version    string
+	Token      string
+	Timestamp  string
+	Method     string
+	Path       string
+	Query      url.Values
+	BodySHA256 string
+	Nonce      string
+}
+
+func (c CanonicalRequest) Message() (string, error) {
+	var parts []string
+	switch c.Version {
+	case SignatureV1, constants.EmptyString:
+		parts = []string{c.Token, c.Timestamp, c.Method, c.Path}
+	case SignatureV2:
+		parts = []string{SignatureV2, c.Token, c.Timestamp, c.Method, c.Path, CanonicalQuery(c.Query), c.BodySHA256}
+	default:
+		return constants.EmptyString, ErrUnsupportedVersion
+	}
+
+	if c.Nonce != constants.EmptyString {
+		parts = append(parts, c.Nonce)
+	}
+	return strings.Join(parts, constants.PipeSeparator), nil
+}
+
+func CanonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, k := range keys {
+		vals := append([]string(nil), q[k]...)
+		sort.Strings(vals)
+		for _, v := range vals {
+			pairs = append(pairs, url.QueryEscape(k)+queryAssign+url.QueryEscape(v))
+		}
+	}
+	return strings.Join(pairs, querySeparator)
+}
+
+func BodySHA256(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+func Sign(message, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(message))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func Verify(message, signature, secret string) bool {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(message))
+	expectedMAC := mac.Sum(nil)
+	sigBytes, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(expectedMAC, sigBytes)
+}
+
+func VerifyAny(message, signature string, secrets []string) bool {
+	for _, secret := range secrets {
+		if Verify(message, signature, secret) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+)
+
+const emptyBodySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+func TestCanonicalRequestMessage(t *testing.T) {
+	base := CanonicalRequest{
+		Token:     "tok",
+		Timestamp: "1700000000",
+		Method:    "GET",
+		Path:      "/api/v1/items",
+	}
+	with := func(change func(*CanonicalRequest)) CanonicalRequest {
+		c := base
+		change(&c)
+		return c
+	}
+
+	tests := []struct {
+		name    string
+		request CanonicalRequest
+		want    string
+	}{
+		{"v1 by default", base, "tok|1700000000|GET|/api/v1/items"},
+		{"v1", with(func(c *CanonicalRequest) { c.Version = SignatureV1 }), "tok|1700000000|GET|/api/v1/items"},
+		{"v1 ignores query and body", with(func(c *CanonicalRequest) {
+			c.Query = url.Values{"a": {"1"}}
+			c.BodySHA256 = emptyBodySHA256
+		}), "tok|1700000000|GET|/api/v1/items"},
+		{"v1 with nonce", with(func(c *CanonicalRequest) { c.Nonce = "n1" }), "tok|1700000000|GET|/api/v1/items|n1"},
+		{"v2 without query and body", with(func(c *CanonicalRequest) {
+			c.Version = SignatureV2
+			c.BodySHA256 = BodySHA256(nil)
+		}), "v2|tok|1700000000|GET|/api/v1/items||" + emptyBodySHA256},
+		{"v2 sorts keys and values", with(func(c *CanonicalRequest) {
+			c.Version = SignatureV2
+			c.Query = url.Values{"sort": {"-price"}, "limit": {"5"}, "category": {"b", "a"}}
+			c.BodySHA256 = emptyBodySHA256
+		}), "v2|tok|1700000000|GET|/api/v1/items|category=a&category=b&limit=5&sort=-price|" + emptyBodySHA256},
+		{"v2 with nonce", with(func(c *CanonicalRequest) {
+			c.Version = SignatureV2
+			c.Method = "POST"
+			c.BodySHA256 = BodySHA256([]byte(`{"name":"Desk"}`))
+			c.Nonce = "n1"
+		}), "v2|tok|1700000000|POST|/api/v1/items||" + BodySHA256([]byte(`{"name":"Desk"}`)) + "|n1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.request.Message()
+			if err != nil {
+				t.Fatalf("Message() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Message() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanonicalRequestUnsupportedVersion(t *testing.T) {
+	_, err := CanonicalRequest{Version: "v3"}.Message()
+	if !errors.Is(err, ErrUnsupportedVersion) {
+		t.Fatalf("Message() error = %v, want %v", err, ErrUnsupportedVersion)
+	}
+}
+
+func TestCanonicalQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"empty", "", ""},
+		{"single", "a=1", "a=1"},
+		{"keys sorted", "b=2&a=1&c=3", "a=1&b=2&c=3"},
+		{"repeated key values sorted", "a=2&a=10&a=1", "a=1&a=10&a=2"},
+		{"order of the raw query does not matter", "x=1&a=2&x=0", "a=2&x=0&x=1"},
+		{"empty value kept", "a=&b=1", "a=&b=1"},
+		{"key without value", "flag&a=1", "a=1&flag="},
+		{"reserved characters escaped", "q=a b&r=x/y&s=1+1", "q=a+b&r=x%2Fy&s=1+1"},
+		{"percent encoding normalised", "q=%61%20b", "q=a+b"},
+		{"unicode", "q=caf%C3%A9", "q=caf%C3%A9"},
+		{"keys sorted bytewise", "b=1&B=2&_=3", "B=2&_=3&b=1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := CanonicalQuery(q); got != tt.want {
+				t.Errorf("CanonicalQuery(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanonicalQueryDoesNotReorderInput(t *testing.T) {
+	q := url.Values{"a": {"2", "1"}}
+	CanonicalQuery(q)
+	if q["a"][0] != "2" || q["a"][1] != "1" {
+		t.Errorf("CanonicalQuery reordered its input: %v", q["a"])
+	}
+}
+
+func TestBodySHA256(t *testing.T) {
+	if got := BodySHA256(nil); got != emptyBodySHA256 {
+		t.Errorf("BodySHA256(nil) = %s", got)
+	}
+	if got := BodySHA256([]byte{}); got != emptyBodySHA256 {
+		t.Errorf("BodySHA256(empty) = %s", got)
+	}
+	if got := BodySHA256([]byte("abc")); got != "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad" {
+		t.Errorf("BodySHA256(abc) = %s", got)
+	}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	message := "tok|1700000000|GET|/api/v1/items"
+	sig := Sign(message, "secret")
+
+	tests := []struct {
+		name      string
+		message   string
+		signature string
+		secrets   []string
+		want      bool
+	}{
+		{"matching secret", message, sig, []string{"secret"}, true},
+		{"any of several secrets", message, sig, []string{"old", "secret"}, true},
+		{"wrong secret", message, sig, []string{"other"}, false},
+		{"no secrets", message, sig, nil, false},
+		{"different message", message + "|n1", sig, []string{"secret"}, false},
+		{"signature not hex", message, "zz" + sig[2:], []string{"secret"}, false},
+		{"truncated signature", message, sig[:len(sig)-2], []string{"secret"}, false},
+		{"empty signature", message, "", []string{"secret"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyAny(tt.message, tt.signature, tt.secrets); got != tt.want {
+				t.Errorf("VerifyAny() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,304 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"production-go-api-template/pkg/auth"
+	"production-go-api-template/pkg/constants"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultTimeout    = 30 * time.Second
+	defaultMaxRetries = 3
+	defaultBackoff    = 200 * time.Millisecond
+	maxRetryWait      = 30 * time.Second
+	nonceLength       = 16
+	baseDecimal       = 10
+	contentTypeJSON   = "application/json"
+	headerContentType = "Content-Type"
+	headerIfMatch     = "If-Match"
+)
+
+// Client calls the API with signed requests. It is safe for concurrent use.
+type Client struct {
+	baseURL    *url.URL
+	token      string
+	secret     string
+	keyID      string
+	version    string
+	nonces     bool
+	http       *http.Client
+	maxRetries int
+	backoff    time.Duration
+	clockSkew  atomic.Int64
+}
+
+func New(baseURL, token, secret string) (*Client, error) {
+	u, err := url.Parse(strings.TrimRight(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if u.Scheme == constants.EmptyString || u.Host == constants.EmptyString {
+		return nil, fmt.Errorf("invalid base URL %q", baseURL)
+	}
+
+	return &Client{
+		baseURL:    u,
+		token:      token,
+		secret:     secret,
+		version:    auth.SignatureV1,
+		http:       &http.Client{Timeout: defaultTimeout},
+		maxRetries: defaultMaxRetries,
+		backoff:    defaultBackoff,
+	}, nil
+}
+
+func (c *Client) WithHTTPClient(hc *http.Client) *Client {
+	c.http = hc
+	return c
+}
+
+// WithRetry sets how often idempotent requests are retried and the base of
+// the exponential backoff between attempts.
+func (c *Client) WithRetry(maxRetries int, backoff time.Duration) *Client {
+	c.maxRetries = maxRetries
+	c.backoff = backoff
+	return c
+}
+
+func (c *Client) WithKeyID(keyID string) *Client {
+	c.keyID = keyID
+	return c
+}
+
+// WithSignatureV2 signs the query string and body digest as well.
+func (c *Client) WithSignatureV2() *Client {
+	c.version = auth.SignatureV2
+	return c
+}
+
+// WithNonces sends a fresh X-Nonce with every attempt, as required by
+// servers running with AUTH_NONCE_REQUIRED.
+func (c *Client) WithNonces() *Client {
+	c.nonces = true
+	return c
+}
+
+// Do sends a signed request and decodes a JSON response into out when out is
+// not nil. GET, PUT and DELETE are retried on network errors, 429 and 5xx
+// gateway errors; every attempt is signed with a fresh timestamp.
+func (c *Client) Do(ctx context.Context, method, path string, query url.Values, in, out any) error {
+	return c.do(ctx, method, path, query, nil, in, out)
+}
+
+// do is Do with extra request headers. A body is sent as JSON unless header
+// sets another Content-Type.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, header http.Header, in, out any) error {
+	header = header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		if header.Get(headerContentType) == constants.EmptyString {
+			header.Set(headerContentType, contentTypeJSON)
+		}
+	}
+
+	retries := constants.ZeroIndex
+	if idempotent(method) {
+		retries = c.maxRetries
+	}
+
+	for attempt := constants.ZeroIndex; ; attempt++ {
+		resp, err := c.send(ctx, method, path, query, header, body)
+		if err == nil && !retryable(resp.StatusCode) {
+			return decodeResponse(resp, out)
+		}
+		if attempt >= retries || ctx.Err() != nil {
+			if err != nil {
+				return err
+			}
+			return decodeResponse(resp, out)
+		}
+
+		wait := c.backoff << attempt
+		if err == nil {
+			if retryAfter := parseRetryAfter(resp.Header); retryAfter > wait {
+				wait = retryAfter
+			}
+			drainAndClose(resp)
+		}
+		if wait > maxRetryWait {
+			wait = maxRetryWait
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Send signs and sends a raw body once, without retries or decoding. It is
+// meant for debugging; the caller must close the response body.
+func (c *Client) Send(ctx context.Context, method, path string, query url.Values, body []byte) (*http.Response, error) {
+	header := http.Header{}
+	if body != nil {
+		header.Set(headerContentType, contentTypeJSON)
+	}
+	return c.send(ctx, method, path, query, header, body)
+}
+
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	u := *c.baseURL
+	u.Path = c.baseURL.Path + path
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if err := c.sign(req, body); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	c.trackClock(resp.Header)
+	return resp, nil
+}
+
+func (c *Client) sign(req *http.Request, body []byte) error {
+	canonical := auth.CanonicalRequest{
+		Version:   c.version,
+		Token:     c.token,
+		Timestamp: strconv.FormatInt(c.now().Unix(), baseDecimal),
+		Method:    req.Method,
+		Path:      req.URL.Path,
+	}
+
+	if c.nonces {
+		nonce, err := newNonce()
+		if err != nil {
+			return err
+		}
+		canonical.Nonce = nonce
+		req.Header.Set(auth.HeaderNonce, nonce)
+	}
+
+	if c.version == auth.SignatureV2 {
+		canonical.Query = req.URL.Query()
+		canonical.BodySHA256 = auth.BodySHA256(body)
+		req.Header.Set(auth.HeaderSignatureVersion, auth.SignatureV2)
+		req.Header.Set(auth.HeaderContentSHA256, canonical.BodySHA256)
+	}
+
+	message, err := canonical.Message()
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set(auth.HeaderAuthorization, auth.BearerPrefix+c.token)
+	req.Header.Set(auth.HeaderTimestamp, canonical.Timestamp)
+	req.Header.Set(auth.HeaderSignature, auth.Sign(message, c.secret))
+	if c.keyID != constants.EmptyString {
+		req.Header.Set(auth.HeaderKeyID, c.keyID)
+	}
+	return nil
+}
+
+// now returns the local time corrected by the offset to the server clock
+// observed in earlier responses, so a drifting local clock does not push
+// timestamps outside the server's allowed skew.
+func (c *Client) now() time.Time {
+	return time.Now().Add(time.Duration(c.clockSkew.Load()))
+}
+
+// trackClock prefers X-Server-Time, which the server sets on authentication
+// failures, and falls back to the Date header of any other response.
+func (c *Client) trackClock(h http.Header) {
+	var serverTime time.Time
+	if seconds, err := strconv.ParseInt(h.Get(auth.HeaderServerTime), baseDecimal, constants.BigInt); err == nil {
+		serverTime = time.Unix(seconds, 0)
+	} else if serverTime, err = http.ParseTime(h.Get("Date")); err != nil {
+		return
+	}
+	c.clockSkew.Store(int64(time.Until(serverTime).Truncate(time.Second)))
+}
+
+func decodeResponse(resp *http.Response, out any) error {
+	defer drainAndClose(resp)
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return newAPIError(resp)
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+func drainAndClose(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+func retryable(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func parseRetryAfter(h http.Header) time.Duration {
+	seconds, err := strconv.Atoi(h.Get("Retry-After"))
+	if err != nil || seconds < constants.ZeroIndex {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func newNonce() (string, error) {
+	b := make([]byte, nonceLength)
+	if _, err := rand.Read(b); err != nil {
+		return constants.EmptyString, err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,158 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"production-go-api-template/pkg/jsonpatch"
+	"strconv"
+	"time"
+)
+
+const itemsPath = "/api/v1/items"
+
+type Item struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Price       float64   `json:"price"`
+	Category    string    `json:"category"`
+	Version     int       `json:"version"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type ItemInput struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Price       float64 `json:"price"`
+	Category    string  `json:"category"`
+}
+
+type ItemList struct {
+	Items      []Item `json:"items"`
+	Total      int    `json:"total"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// ListOptions selects a page of items. Zero values use the server defaults;
+// pass the NextCursor of the previous page, with the same Sort, to continue.
+// Sort is a field name such as "price", prefixed with "-" for descending.
+type ListOptions struct {
+	Limit       int
+	Cursor      string
+	Sort        string
+	Category    string
+	MinPrice    *float64
+	MaxPrice    *float64
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+}
+
+func (o ListOptions) values() url.Values {
+	q := url.Values{}
+	if o.Limit > 0 {
+		q.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.Cursor != "" {
+		q.Set("cursor", o.Cursor)
+	}
+	if o.Sort != "" {
+		q.Set("sort", o.Sort)
+	}
+	if o.Category != "" {
+		q.Set("category", o.Category)
+	}
+	if o.MinPrice != nil {
+		q.Set("min_price", strconv.FormatFloat(*o.MinPrice, 'f', -1, 64))
+	}
+	if o.MaxPrice != nil {
+		q.Set("max_price", strconv.FormatFloat(*o.MaxPrice, 'f', -1, 64))
+	}
+	if !o.CreatedFrom.IsZero() {
+		q.Set("created_from", o.CreatedFrom.Format(time.RFC3339))
+	}
+	if !o.CreatedTo.IsZero() {
+		q.Set("created_to", o.CreatedTo.Format(time.RFC3339))
+	}
+	return q
+}
+
+func (c *Client) CreateItem(ctx context.Context, in ItemInput) (Item, error) {
+	var item Item
+	err := c.Do(ctx, http.MethodPost, itemsPath, nil, in, &item)
+	return item, err
+}
+
+func (c *Client) ListItems(ctx context.Context, opts ListOptions) (ItemList, error) {
+	var list ItemList
+	err := c.Do(ctx, http.MethodGet, itemsPath, opts.values(), nil, &list)
+	return list, err
+}
+
+type SearchResult struct {
+	Item
+	Score   float64 `json:"score"`
+	Snippet string  `json:"snippet"`
+}
+
+type SearchResults struct {
+	Results []SearchResult `json:"results"`
+	Total   int            `json:"total"`
+}
+
+// SearchItems runs a full-text search; limit 0 uses the server default.
+func (c *Client) SearchItems(ctx context.Context, text string, limit int) (SearchResults, error) {
+	q := url.Values{"q": {text}}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+
+	var results SearchResults
+	err := c.Do(ctx, http.MethodGet, itemsPath+"/search", q, nil, &results)
+	return results, err
+}
+
+func (c *Client) GetItem(ctx context.Context, id int) (Item, error) {
+	var item Item
+	err := c.Do(ctx, http.MethodGet, itemPath(id), nil, nil, &item)
+	return item, err
+}
+
+// UpdateItem replaces an item. A non-zero version makes the write fail with
+// ErrPreconditionFailed if the item is no longer at that version; zero
+// writes unconditionally.
+func (c *Client) UpdateItem(ctx context.Context, id, version int, in ItemInput) (Item, error) {
+	var item Item
+	err := c.do(ctx, http.MethodPut, itemPath(id), nil, ifMatch(version), in, &item)
+	return item, err
+}
+
+// PatchItem changes only the given fields with a JSON merge patch; a nil
+// value clears an optional field such as description. version works as for
+// UpdateItem.
+func (c *Client) PatchItem(ctx context.Context, id, version int, fields map[string]any) (Item, error) {
+	header := ifMatch(version)
+	header.Set(headerContentType, jsonpatch.MediaTypeMergePatch)
+
+	var item Item
+	err := c.do(ctx, http.MethodPatch, itemPath(id), nil, header, fields, &item)
+	return item, err
+}
+
+// DeleteItem deletes an item; version works as for UpdateItem.
+func (c *Client) DeleteItem(ctx context.Context, id, version int) error {
+	return c.do(ctx, http.MethodDelete, itemPath(id), nil, ifMatch(version), nil, nil)
+}
+
+func itemPath(id int) string {
+	return itemsPath + "/" + strconv.Itoa(id)
+}
+
+func ifMatch(version int) http.Header {
+	header := http.Header{}
+	if version > 0 {
+		header.Set(headerIfMatch, `"`+strconv.Itoa(version)+`"`)
+	}
+	return header
+}
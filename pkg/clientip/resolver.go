@@ -0,0 +1,180 @@
+package clientip
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"production-go-api-template/pkg/constants"
+	"strings"
+)
+
+const (
+	headerForwarded     = "Forwarded"
+	headerXForwardedFor = "X-Forwarded-For"
+	headerXRealIP       = "X-Real-IP"
+
+	forwardedForParam = "for="
+	listSeparator     = ","
+	paramSeparator    = ";"
+)
+
+// Resolver determines the originating client address of a request. Only the
+// one forwarding header that the trusted proxies set is read, and only when
+// the direct peer is one of those proxies; otherwise the peer address is
+// returned unchanged. Reading a single header keeps a client from choosing
+// which header wins by sending one the proxy does not overwrite.
+type Resolver struct {
+	trusted []*net.IPNet
+	header  string
+}
+
+// NewResolver builds a resolver for the given forwarding header, matched
+// case-insensitively against Forwarded, X-Forwarded-For and X-Real-IP.
+func NewResolver(trustedProxies []string, header string) (*Resolver, error) {
+	nets, err := ParseCIDRs(trustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted proxy: %w", err)
+	}
+	canonical := http.CanonicalHeaderKey(header)
+	switch canonical {
+	case headerForwarded, headerXForwardedFor, http.CanonicalHeaderKey(headerXRealIP):
+	default:
+		return nil, fmt.Errorf("unsupported client IP header %q", header)
+	}
+	return &Resolver{trusted: nets, header: canonical}, nil
+}
+
+// ParseCIDRs parses CIDR notation and bare addresses, which are treated as
+// single-host networks. Empty entries are skipped.
+func ParseCIDRs(values []string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == constants.EmptyString {
+			continue
+		}
+		if !strings.Contains(v, "/") {
+			ip := net.ParseIP(v)
+			if ip == nil {
+				return nil, fmt.Errorf("%q is not an IP address or CIDR", v)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(v)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+func (res *Resolver) ClientIP(r *http.Request) string {
+	peer := PeerIP(r)
+	if !res.isTrusted(peer) {
+		return peer
+	}
+
+	// Proxies may append a new header line instead of extending the last
+	// one, so every line takes part in the chain in the order received.
+	value := strings.Join(r.Header.Values(res.header), listSeparator)
+	var chain []string
+	if res.header == headerForwarded {
+		chain = forwardedChain(value)
+	} else {
+		chain = splitList(value)
+	}
+	if len(chain) == constants.ZeroIndex {
+		return peer
+	}
+	return res.firstUntrusted(chain, peer)
+}
+
+func PeerIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// firstUntrusted walks the proxy chain from the closest hop outwards and
+// returns the first address that is not a trusted proxy. Entries left of it
+// were supplied by the client and cannot be trusted.
+func (res *Resolver) firstUntrusted(chain []string, peer string) string {
+	for i := len(chain) - constants.FirstIndex; i >= constants.ZeroIndex; i-- {
+		ip := net.ParseIP(chain[i])
+		if ip == nil {
+			return peer
+		}
+		if !res.isTrusted(ip.String()) {
+			return ip.String()
+		}
+	}
+	if ip := net.ParseIP(chain[constants.ZeroIndex]); ip != nil {
+		return ip.String()
+	}
+	return peer
+}
+
+func (res *Resolver) isTrusted(ipStr string) bool {
+	ip := net.ParseIP(ipStr)
+	if ip == nil {
+		return false
+	}
+	for _, n := range res.trusted {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func splitList(header string) []string {
+	var out []string
+	for _, part := range strings.Split(header, listSeparator) {
+		if part = strings.TrimSpace(part); part != constants.EmptyString {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// forwardedChain extracts the for= node of every RFC 7239 element. Obfuscated
+// identifiers, "unknown" and elements without for= are kept as unparsable
+// nodes so that they stop the chain walk instead of shifting it.
+func forwardedChain(header string) []string {
+	var chain []string
+	for _, element := range splitList(header) {
+		node := constants.EmptyString
+		for _, pair := range strings.Split(element, paramSeparator) {
+			pair = strings.TrimSpace(pair)
+			if len(pair) < len(forwardedForParam) || !strings.EqualFold(pair[:len(forwardedForParam)], forwardedForParam) {
+				continue
+			}
+			node = forwardedNode(pair[len(forwardedForParam):])
+			break
+		}
+		chain = append(chain, node)
+	}
+	return chain
+}
+
+func forwardedNode(node string) string {
+	node = strings.Trim(node, `"`)
+	if strings.HasPrefix(node, "[") {
+		if end := strings.Index(node, "]"); end > 0 {
+			return node[1:end]
+		}
+		return node
+	}
+	if host, _, err := net.SplitHostPort(node); err == nil {
+		return host
+	}
+	return node
+}
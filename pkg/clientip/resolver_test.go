@@ -0,0 +1,134 @@
+package clientip
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var testProxies = []string{"10.0.0.0/8", "2001:db8:ffff::1"}
+
+func newRequest(remoteAddr string, header string, lines ...string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = remoteAddr
+	for _, line := range lines {
+		r.Header.Add(header, line)
+	}
+	return r
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     string
+		remoteAddr string
+		lines      []string
+		want       string
+	}{
+		{"no proxy header", headerXForwardedFor, "203.0.113.7:4000", nil, "203.0.113.7"},
+		{"untrusted peer is the client", headerXForwardedFor, "203.0.113.7:4000", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"untrusted IPv6 peer", headerXForwardedFor, "[2001:db8::7]:4000", []string{"198.51.100.1"}, "2001:db8::7"},
+		{"trusted peer without header", headerXForwardedFor, "10.0.0.1:4000", nil, "10.0.0.1"},
+		{"single hop", headerXForwardedFor, "10.0.0.1:4000", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"client prefix is ignored", headerXForwardedFor, "10.0.0.1:4000", []string{"1.2.3.4, 198.51.100.1"}, "198.51.100.1"},
+		{"multiple trusted hops", headerXForwardedFor, "10.0.0.1:4000", []string{"1.2.3.4, 198.51.100.1, 10.0.0.3, 10.0.0.2"}, "198.51.100.1"},
+		{"multiple header lines", headerXForwardedFor, "10.0.0.1:4000", []string{"1.2.3.4", "198.51.100.1", "10.0.0.2"}, "198.51.100.1"},
+		{"only the last line is trusted", headerXForwardedFor, "10.0.0.1:4000", []string{"198.51.100.1", "1.2.3.4"}, "1.2.3.4"},
+		{"all hops trusted", headerXForwardedFor, "10.0.0.1:4000", []string{"10.0.0.3, 10.0.0.2"}, "10.0.0.3"},
+		{"garbage stops at the proxy", headerXForwardedFor, "10.0.0.1:4000", []string{"198.51.100.1, nonsense"}, "10.0.0.1"},
+		{"IPv6 hop", headerXForwardedFor, "[2001:db8:ffff::1]:4000", []string{"2001:db8::7"}, "2001:db8::7"},
+
+		{"forwarded single hop", headerForwarded, "10.0.0.1:4000", []string{"for=198.51.100.1;proto=https"}, "198.51.100.1"},
+		{"forwarded multiple hops", headerForwarded, "10.0.0.1:4000", []string{"for=1.2.3.4, for=198.51.100.1;by=10.0.0.1, for=10.0.0.2"}, "198.51.100.1"},
+		{"forwarded multiple lines", headerForwarded, "10.0.0.1:4000", []string{"for=1.2.3.4", "for=198.51.100.1"}, "198.51.100.1"},
+		{"forwarded parameter order and case", headerForwarded, "10.0.0.1:4000", []string{"proto=https;FOR=198.51.100.1"}, "198.51.100.1"},
+		{"forwarded IPv6 in brackets", headerForwarded, "10.0.0.1:4000", []string{`for="[2001:db8::7]:4711"`}, "2001:db8::7"},
+		{"forwarded IPv4 with port", headerForwarded, "10.0.0.1:4000", []string{`for="198.51.100.1:4711"`}, "198.51.100.1"},
+		{"forwarded unknown stops at the proxy", headerForwarded, "10.0.0.1:4000", []string{"for=198.51.100.1, for=unknown"}, "10.0.0.1"},
+		{"forwarded obfuscated stops at the proxy", headerForwarded, "10.0.0.1:4000", []string{"for=198.51.100.1, for=_hidden"}, "10.0.0.1"},
+		{"forwarded element without for stops at the proxy", headerForwarded, "10.0.0.1:4000", []string{"for=198.51.100.1, proto=https"}, "10.0.0.1"},
+		{"forwarded from untrusted peer", headerForwarded, "203.0.113.7:4000", []string{"for=198.51.100.1"}, "203.0.113.7"},
+
+		{"real ip", headerXRealIP, "10.0.0.1:4000", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"real ip lines walked like a chain", headerXRealIP, "10.0.0.1:4000", []string{"1.2.3.4", "198.51.100.1"}, "198.51.100.1"},
+		{"real ip from untrusted peer", headerXRealIP, "203.0.113.7:4000", []string{"198.51.100.1"}, "203.0.113.7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := NewResolver(testProxies, tt.header)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := res.ClientIP(newRequest(tt.remoteAddr, tt.header, tt.lines...)); got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientIPReadsOnlyTheConfiguredHeader(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured string
+		real       string
+		spoofed    string
+		value      string
+	}{
+		{"forwarded while reading x-forwarded-for", "x-forwarded-for", "198.51.100.1", headerForwarded, "for=1.2.3.4"},
+		{"x-real-ip while reading x-forwarded-for", "x-forwarded-for", "198.51.100.1", headerXRealIP, "1.2.3.4"},
+		{"x-forwarded-for while reading forwarded", "forwarded", "for=198.51.100.1", headerXForwardedFor, "1.2.3.4"},
+		{"forwarded while reading x-real-ip", "x-real-ip", "198.51.100.1", headerForwarded, "for=1.2.3.4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := NewResolver(testProxies, tt.configured)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := newRequest("10.0.0.1:4000", tt.configured, tt.real)
+			r.Header.Set(tt.spoofed, tt.value)
+			if got := res.ClientIP(r); got != "198.51.100.1" {
+				t.Errorf("ClientIP() = %q, want 198.51.100.1", got)
+			}
+		})
+	}
+}
+
+func TestNewResolverErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		proxies []string
+		header  string
+	}{
+		{"unsupported header", nil, "cf-connecting-ip"},
+		{"empty header", nil, ""},
+		{"invalid proxy", []string{"10.0.0.0/33"}, "x-forwarded-for"},
+		{"proxy is not an address", []string{"proxy.local"}, "x-forwarded-for"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewResolver(tt.proxies, tt.header); err == nil {
+				t.Fatal("NewResolver() expected an error")
+			}
+		})
+	}
+}
+
+func TestParseCIDRs(t *testing.T) {
+	nets, err := ParseCIDRs([]string{" 10.0.0.0/8 ", "", "192.0.2.1", "2001:db8::1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"10.0.0.0/8", "192.0.2.1/32", "2001:db8::1/128"}
+	if len(nets) != len(want) {
+		t.Fatalf("ParseCIDRs() returned %d networks, want %d", len(nets), len(want))
+	}
+	for i, n := range nets {
+		if n.String() != want[i] {
+			t.Errorf("network %d = %s, want %s", i, n, want[i])
+		}
+	}
+}
@@ -8,4 +8,14 @@ const (
 	CtxKeyLogger ctxKey = "logger"
 
 	CtxKeyRequestID ctxKey = "request_id"
+
+	CtxKeyClientID ctxKey = "client_id"
+
+	CtxKeyClientIP ctxKey = "client_ip"
+
+	CtxKeyClaims ctxKey = "claims"
+
+	CtxKeyScopes ctxKey = "scopes"
+
+	CtxKeyRequestLog ctxKey = "request_log"
 )
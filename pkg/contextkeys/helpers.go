@@ -12,3 +12,21 @@ func GetRequestID(ctx context.Context) string {
 	}
 	return ""
 }
+
+func GetClientID(ctx context.Context) string {
+	if clientID := ctx.Value(CtxKeyClientID); clientID != nil {
+		if id, ok := clientID.(string); ok {
+			return id
+		}
+	}
+	return ""
+}
+
+func GetClientIP(ctx context.Context) string {
+	if clientIP := ctx.Value(CtxKeyClientIP); clientIP != nil {
+		if ip, ok := clientIP.(string); ok {
+			return ip
+		}
+	}
+	return ""
+}
@@ -0,0 +1,62 @@
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	MediaTypeMergePatch = "application/merge-patch+json"
+	MediaTypeJSONPatch  = "application/json-patch+json"
+)
+
+// MergePatch applies an RFC 7396 merge patch to doc: members of patch
+// replace those of doc, null removes a member and objects merge recursively.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid document: %w", err)
+	}
+	p, err := decode(patch)
+	if err != nil {
+		return nil, fmt.Errorf("invalid merge patch: %w", err)
+	}
+	return json.Marshal(merge(target, p))
+}
+
+func merge(target, patch any) any {
+	members, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	obj, ok := target.(map[string]any)
+	if !ok {
+		obj = make(map[string]any, len(members))
+	}
+	for name, value := range members {
+		if value == nil {
+			delete(obj, name)
+			continue
+		}
+		obj[name] = merge(obj[name], value)
+	}
+	return obj
+}
+
+// decode keeps numbers as json.Number so patching does not change their
+// precision or formatting.
+func decode(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("unexpected data after the JSON value")
+	}
+	return v, nil
+}
@@ -0,0 +1,142 @@
+package jsonpatch
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		// RFC 6902 Appendix A.
+		{"A.1 add object member", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+		{"A.2 add array element", `{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{"A.3 remove object member", `{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{"A.4 remove array element", `{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{"A.5 replace", `{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{"A.6 move value", `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			`[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{"A.7 move array element", `{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+		{"A.8 test", `{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`, `{"baz":"qux","foo":["a",2,"c"]}`},
+		{"A.10 add nested member object", `{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, `{"child":{"grandchild":{}},"foo":"bar"}`},
+		{"A.11 ignore unrecognized members", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux","xyz":123}]`, `{"baz":"qux","foo":"bar"}`},
+		{"A.14 escape ordering", `{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10}]`, `{"/":9,"~1":10}`},
+		{"A.16 add array value", `{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`},
+
+		{"add replaces existing member", `{"a":1}`, `[{"op":"add","path":"/a","value":2}]`, `{"a":2}`},
+		{"add at end index", `{"a":[1,2]}`, `[{"op":"add","path":"/a/2","value":3}]`, `{"a":[1,2,3]}`},
+		{"add with dash appends", `{"a":[1,2]}`, `[{"op":"add","path":"/a/-","value":3}]`, `{"a":[1,2,3]}`},
+		{"add to empty array with dash", `{"a":[]}`, `[{"op":"add","path":"/a/-","value":1}]`, `{"a":[1]}`},
+		{"add null value", `{"a":1}`, `[{"op":"add","path":"/b","value":null}]`, `{"a":1,"b":null}`},
+		{"add empty member name", `{}`, `[{"op":"add","path":"/","value":1}]`, `{"":1}`},
+		{"slash escape", `{}`, `[{"op":"add","path":"/a~1b","value":1}]`, `{"a/b":1}`},
+		{"replace root", `{"a":1}`, `[{"op":"replace","path":"","value":[1]}]`, `[1]`},
+		{"add root", `{"a":1}`, `[{"op":"add","path":"","value":{"b":2}}]`, `{"b":2}`},
+		{"replace array element", `[1,2,3]`, `[{"op":"replace","path":"/1","value":9}]`, `[1,9,3]`},
+		{"move to same path", `{"a":1}`, `[{"op":"move","from":"/a","path":"/a"}]`, `{"a":1}`},
+		{"move into sibling", `{"a":{"b":1},"c":{}}`, `[{"op":"move","from":"/a","path":"/c/a"}]`, `{"c":{"a":{"b":1}}}`},
+		{"move to end with dash", `[1,2,3]`, `[{"op":"move","from":"/0","path":"/-"}]`, `[2,3,1]`},
+		{"copy", `{"a":{"b":1}}`, `[{"op":"copy","from":"/a","path":"/c"}]`, `{"a":{"b":1},"c":{"b":1}}`},
+		{"copy is deep", `{"a":{"b":1}}`, `[{"op":"copy","from":"/a","path":"/c"},{"op":"replace","path":"/c/b","value":2}]`, `{"a":{"b":1},"c":{"b":2}}`},
+		{"test whole document", `{"a":[1,{"b":2}]}`, `[{"op":"test","path":"","value":{"a":[1,{"b":2}]}}]`, `{"a":[1,{"b":2}]}`},
+		{"test integer against float", `{"a":1}`, `[{"op":"test","path":"/a","value":1.0}]`, `{"a":1}`},
+		{"test exponent notation", `{"a":100}`, `[{"op":"test","path":"/a","value":1e2}]`, `{"a":100}`},
+		{"test null", `{"a":null}`, `[{"op":"test","path":"/a","value":null}]`, `{"a":null}`},
+		{"numbers keep their form", `{"a":1.50,"b":12345678901234567890}`, `[{"op":"add","path":"/c","value":0.10}]`, `{"a":1.50,"b":12345678901234567890,"c":0.10}`},
+		{"operations apply in order", `{}`, `[{"op":"add","path":"/a","value":[]},{"op":"add","path":"/a/-","value":1},{"op":"move","from":"/a","path":"/b"}]`, `{"b":[1]}`},
+		{"empty patch", `{"a":1}`, `[]`, `{"a":1}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply([]byte(tt.doc), []byte(tt.patch))
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Apply() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+	}{
+		{"A.12 add to nonexistent target", `{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`},
+		{"remove missing member", `{"a":1}`, `[{"op":"remove","path":"/b"}]`},
+		{"remove root", `{"a":1}`, `[{"op":"remove","path":""}]`},
+		{"replace missing member", `{"a":1}`, `[{"op":"replace","path":"/b","value":2}]`},
+		{"replace with dash", `[1]`, `[{"op":"replace","path":"/-","value":2}]`},
+		{"remove with dash", `[1]`, `[{"op":"remove","path":"/-"}]`},
+		{"leading zero index", `{"a":[1,2]}`, `[{"op":"add","path":"/a/01","value":3}]`},
+		{"leading zero on remove", `{"a":[1,2]}`, `[{"op":"remove","path":"/a/01"}]`},
+		{"negative index", `[1,2]`, `[{"op":"remove","path":"/-1"}]`},
+		{"plus sign index", `[1,2]`, `[{"op":"remove","path":"/+1"}]`},
+		{"add past the end", `{"a":[1,2]}`, `[{"op":"add","path":"/a/3","value":3}]`},
+		{"remove past the end", `{"a":[1,2]}`, `[{"op":"remove","path":"/a/2"}]`},
+		{"index into scalar", `{"a":1}`, `[{"op":"add","path":"/a/b","value":2}]`},
+		{"move into own child", `{"a":{"b":{}}}`, `[{"op":"move","from":"/a","path":"/a/b/c"}]`},
+		{"move into own array child", `{"a":[[]]}`, `[{"op":"move","from":"/a","path":"/a/0/0"}]`},
+		{"move from missing", `{"a":1}`, `[{"op":"move","from":"/b","path":"/c"}]`},
+		{"copy from missing", `{"a":1}`, `[{"op":"copy","from":"/b","path":"/c"}]`},
+		{"pointer without slash", `{"a":1}`, `[{"op":"remove","path":"a"}]`},
+		{"from without slash", `{"a":1}`, `[{"op":"copy","from":"a","path":"/b"}]`},
+		{"missing value", `{"a":1}`, `[{"op":"add","path":"/b"}]`},
+		{"unknown operation", `{"a":1}`, `[{"op":"merge","path":"/a","value":1}]`},
+		{"patch is not an array", `{"a":1}`, `{"op":"add","path":"/b","value":1}`},
+		{"invalid document", `{"a":`, `[]`},
+		{"trailing data in document", `{"a":1} {}`, `[]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := Apply([]byte(tt.doc), []byte(tt.patch)); err == nil {
+				t.Fatalf("Apply() = %s, want an error", got)
+			}
+		})
+	}
+}
+
+func TestApplyTestFailures(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+	}{
+		{"A.9 test value mismatch", `{"baz":"qux"}`, `[{"op":"test","path":"/baz","value":"bar"}]`},
+		{"A.15 string is not number", `{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":"10"}]`},
+		{"different numbers", `{"a":1}`, `[{"op":"test","path":"/a","value":1.5}]`},
+		{"null is not false", `{"a":null}`, `[{"op":"test","path":"/a","value":false}]`},
+		{"array order matters", `{"a":[1,2]}`, `[{"op":"test","path":"/a","value":[2,1]}]`},
+		{"extra member", `{"a":{"b":1}}`, `[{"op":"test","path":"/a","value":{"b":1,"c":2}}]`},
+		{"earlier operations are discarded", `{"a":1}`, `[{"op":"replace","path":"/a","value":2},{"op":"test","path":"/a","value":1}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Apply([]byte(tt.doc), []byte(tt.patch))
+			if !errors.Is(err, ErrTestFailed) {
+				t.Fatalf("Apply() error = %v, want %v", err, ErrTestFailed)
+			}
+		})
+	}
+}
+
+func TestApplyDoesNotChangeInput(t *testing.T) {
+	doc := []byte(`{"a":[1,2,3]}`)
+	if _, err := Apply(doc, []byte(`[{"op":"remove","path":"/a/0"}]`)); err != nil {
+		t.Fatal(err)
+	}
+	if string(doc) != `{"a":[1,2,3]}` {
+		t.Errorf("document changed to %s", doc)
+	}
+}
@@ -6,8 +6,15 @@ import (
 )
 
 func Mount(mux *http.ServeMux, prefix string, handler http.Handler) {
+	p, wrapper := mountHandler(prefix, handler)
+
+	mux.Handle(p, wrapper)
+	mux.Handle(p+"/", wrapper)
+}
+
+func mountHandler(prefix string, handler http.Handler) (string, http.Handler) {
 	p := strings.TrimRight(prefix, "/")
-	
+
 	wrapper := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path == p {
 			originalPath := r.URL.Path
@@ -18,7 +25,6 @@ func Mount(mux *http.ServeMux, prefix string, handler http.Handler) {
 		}
 		http.StripPrefix(p, handler).ServeHTTP(w, r)
 	})
-	
-	mux.Handle(p, wrapper)
-	mux.Handle(p+"/", wrapper)
+
+	return p, wrapper
 }
@@ -0,0 +1,80 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+)
+
+type AuthPolicy string
+
+const (
+	PolicyPublic        AuthPolicy = "public"
+	PolicyAuthenticated AuthPolicy = "authenticated"
+	PolicyAdmin         AuthPolicy = "admin"
+)
+
+type Route struct {
+	Pattern string
+	Policy  AuthPolicy
+}
+
+// Routes is a ServeMux whose routes are registered together with an auth
+// policy. Each route is wrapped in the middleware of its policy, so whether a
+// path is public is decided where the route is declared.
+type Routes struct {
+	mux      *http.ServeMux
+	policies map[AuthPolicy]func(http.Handler) http.Handler
+	routes   []Route
+}
+
+// NewRoutes takes the middleware for every policy. PolicyPublic may be left
+// out, in which case public routes are served without middleware. Paths
+// that match no route fall through to PolicyAuthenticated, so unauthenticated
+// callers cannot probe which routes exist.
+func NewRoutes(policies map[AuthPolicy]func(http.Handler) http.Handler) *Routes {
+	rt := &Routes{
+		mux:      http.NewServeMux(),
+		policies: policies,
+	}
+	rt.mux.Handle("/", rt.wrap(PolicyAuthenticated, http.NotFoundHandler()))
+	return rt
+}
+
+func (rt *Routes) Handle(pattern string, policy AuthPolicy, handler http.Handler) {
+	rt.mux.Handle(pattern, rt.wrap(policy, handler))
+	rt.routes = append(rt.routes, Route{Pattern: pattern, Policy: policy})
+}
+
+func (rt *Routes) HandleFunc(pattern string, policy AuthPolicy, handler http.HandlerFunc) {
+	rt.Handle(pattern, policy, handler)
+}
+
+// Mount works like the package level Mount. The policy wraps the handler
+// before the prefix is stripped so authentication sees the full path.
+func (rt *Routes) Mount(prefix string, policy AuthPolicy, handler http.Handler) {
+	p, wrapper := mountHandler(prefix, handler)
+	wrapped := rt.wrap(policy, wrapper)
+
+	rt.mux.Handle(p, wrapped)
+	rt.mux.Handle(p+"/", wrapped)
+	rt.routes = append(rt.routes, Route{Pattern: p + "/", Policy: policy})
+}
+
+func (rt *Routes) Routes() []Route {
+	return append([]Route(nil), rt.routes...)
+}
+
+func (rt *Routes) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rt.mux.ServeHTTP(w, r)
+}
+
+func (rt *Routes) wrap(policy AuthPolicy, handler http.Handler) http.Handler {
+	mw, ok := rt.policies[policy]
+	if !ok {
+		if policy == PolicyPublic {
+			return handler
+		}
+		panic(fmt.Sprintf("router: no middleware for auth policy %q", policy))
+	}
+	return mw(handler)
+}